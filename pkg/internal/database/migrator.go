@@ -16,17 +16,41 @@ var AutoMaintainRange = []any{
 	&models.PollAnswer{},
 	&models.PostFlag{},
 	&models.PostView{},
+	&models.PostBookmark{},
+	&models.PostCollection{},
+	&models.Mute{},
 }
 
 func RunMigration(source *gorm.DB) error {
+	// Only the reports could collapse the post before the moderators were able to, mark them once
+	markReportCollapses := !source.Migrator().HasColumn(&models.Post{}, "is_collapsed_by_reports")
+
 	if err := source.AutoMigrate(
 		append(
 			AutoMaintainRange,
 			&models.Reaction{},
+			&models.FediverseFollower{},
+			&models.FediverseDelivery{},
+			&models.FediverseFriendCursor{},
+			&models.PostRevision{},
+			&models.PostCollectionItem{},
+			&models.AuditLog{},
 		)...,
 	); err != nil {
 		return err
 	}
 
+	if err := migratePostSearchVector(source); err != nil {
+		return err
+	}
+	if markReportCollapses {
+		if err := source.Exec("UPDATE posts SET is_collapsed_by_reports = true WHERE is_collapsed = true").Error; err != nil {
+			return err
+		}
+	}
+	if err := migrateSubscriptionUniqueness(source); err != nil {
+		return err
+	}
+
 	return nil
 }
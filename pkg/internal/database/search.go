@@ -0,0 +1,110 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// SearchConfigs maps the post language to the text search config of PostgreSQL
+// The keys are the language names detected by lingua and the ISO 639-1 codes used by the fediverse posts
+// The languages not in the map, including the CJK languages, will use the simple config
+var SearchConfigs = map[string]string{
+	"arabic": "arabic", "ar": "arabic",
+	"danish": "danish", "da": "danish",
+	"dutch": "dutch", "nl": "dutch",
+	"english": "english", "en": "english",
+	"finnish": "finnish", "fi": "finnish",
+	"french": "french", "fr": "french",
+	"german": "german", "de": "german",
+	"greek": "greek", "el": "greek",
+	"hungarian": "hungarian", "hu": "hungarian",
+	"indonesian": "indonesian", "id": "indonesian",
+	"irish": "irish", "ga": "irish",
+	"italian": "italian", "it": "italian",
+	"lithuanian": "lithuanian", "lt": "lithuanian",
+	"bokmal": "norwegian", "nynorsk": "norwegian", "no": "norwegian", "nb": "norwegian", "nn": "norwegian",
+	"portuguese": "portuguese", "pt": "portuguese",
+	"romanian": "romanian", "ro": "romanian",
+	"russian": "russian", "ru": "russian",
+	"spanish": "spanish", "es": "spanish",
+	"swedish": "swedish", "sv": "swedish",
+	"tamil": "tamil", "ta": "tamil",
+	"turkish": "turkish", "tr": "turkish",
+}
+
+const DefaultSearchConfig = "simple"
+
+// searchLanguageAliases pairs the languages using the simple config, they cannot be paired through SearchConfigs
+var searchLanguageAliases = map[string][]string{
+	"chinese": {"chinese", "zh"}, "zh": {"chinese", "zh"},
+	"japanese": {"japanese", "ja"}, "ja": {"japanese", "ja"},
+	"korean": {"korean", "ko"}, "ko": {"korean", "ko"},
+}
+
+// GetLanguageAliases returns all the forms of the language stored in the posts
+// The local posts store the names detected by lingua and the imported posts store the ISO 639-1 codes
+func GetLanguageAliases(language string) []string {
+	language = strings.ToLower(language)
+	if aliases, ok := searchLanguageAliases[language]; ok {
+		return aliases
+	}
+	config, ok := SearchConfigs[language]
+	if !ok {
+		return []string{language}
+	}
+
+	var aliases []string
+	for alias, value := range SearchConfigs {
+		if value == config {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+func GetSearchConfig(language string) string {
+	if config, ok := SearchConfigs[strings.ToLower(language)]; ok {
+		return config
+	}
+	return DefaultSearchConfig
+}
+
+// GetSearchConfigExpr returns the SQL expression to pick the text search config by the language column
+func GetSearchConfigExpr() string {
+	languages := make([]string, 0, len(SearchConfigs))
+	for language := range SearchConfigs {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	cases := make([]string, 0, len(languages))
+	for _, language := range languages {
+		cases = append(cases, fmt.Sprintf("WHEN '%s' THEN '%s'::regconfig", language, SearchConfigs[language]))
+	}
+	return fmt.Sprintf(
+		"CASE lower(coalesce(language, '')) %s ELSE '%s'::regconfig END",
+		strings.Join(cases, " "),
+		DefaultSearchConfig,
+	)
+}
+
+// The search vector is a generated column, so it always stays in sync with the post body
+// Changing the configs above will not affect the existing column, it needs to be dropped and migrated again
+func migratePostSearchVector(source *gorm.DB) error {
+	if err := source.Exec(fmt.Sprintf(
+		`ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+			setweight(to_tsvector(%[1]s, coalesce(body->>'title', '')), 'A') ||
+			setweight(to_tsvector(%[1]s, coalesce(body->>'description', '')), 'B') ||
+			setweight(to_tsvector(%[1]s, coalesce(body->>'content', '')), 'C')
+		) STORED`,
+		GetSearchConfigExpr(),
+	)).Error; err != nil {
+		return err
+	}
+
+	return source.Exec("CREATE INDEX IF NOT EXISTS idx_posts_search_vector ON posts USING GIN (search_vector)").Error
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"gorm.io/gorm"
+)
+
+const subscriptionPublisherIndex = "idx_subscriptions_publisher"
+
+// The subscriptions are soft deleted, so the unique index only covers the ones still alive
+// The duplicates created before the index existed are removed first, and the counters are fixed once after that
+func migrateSubscriptionUniqueness(source *gorm.DB) error {
+	if source.Migrator().HasIndex(&models.Subscription{}, subscriptionPublisherIndex) {
+		return nil
+	}
+
+	return source.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`UPDATE subscriptions SET deleted_at = now()
+			WHERE account_id IS NOT NULL AND deleted_at IS NULL AND id NOT IN (
+				SELECT MIN(id) FROM subscriptions
+				WHERE account_id IS NOT NULL AND deleted_at IS NULL
+				GROUP BY follower_id, account_id
+			)`).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(
+			"CREATE UNIQUE INDEX " + subscriptionPublisherIndex + " ON subscriptions (follower_id, account_id) WHERE account_id IS NOT NULL AND deleted_at IS NULL",
+		).Error; err != nil {
+			return err
+		}
+		_, err := RecountPublisherFollowers(tx)
+		return err
+	})
+}
+
+// RecountPublisherFollowers fixes the follower counters by the subscriptions, returns how many publishers were changed
+// Pass the publisher ids to only recount them, otherwise all the publishers will be checked
+func RecountPublisherFollowers(tx *gorm.DB, publishers ...uint) (int64, error) {
+	query := `UPDATE publishers SET total_followers = counted.followers
+		FROM (
+			SELECT publishers.id, COUNT(subscriptions.id) AS followers
+			FROM publishers LEFT JOIN subscriptions ON subscriptions.account_id = publishers.id AND subscriptions.deleted_at IS NULL
+			GROUP BY publishers.id
+		) AS counted
+		WHERE publishers.id = counted.id AND publishers.total_followers != counted.followers`
+	var result *gorm.DB
+	if len(publishers) > 0 {
+		result = tx.Exec(query+" AND publishers.id IN ?", publishers)
+	} else {
+		result = tx.Exec(query)
+	}
+	return result.RowsAffected, result.Error
+}
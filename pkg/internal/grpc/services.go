@@ -28,14 +28,29 @@ func (v *App) BroadcastEvent(ctx context.Context, in *proto.EventInfo) (*proto.E
 			if err := jsoniter.Unmarshal(in.GetData(), &data); err != nil {
 				break
 			}
+			// The publishers the account followed, their counters need to be fixed after the subscriptions are gone
+			var followed []uint
+			database.C.Model(&models.Subscription{}).
+				Where("follower_id = ? AND account_id IS NOT NULL", data.ID).
+				Pluck("account_id", &followed)
+
 			tx := database.C.Begin()
 			for _, model := range database.AutoMaintainRange {
 				switch model.(type) {
+				case *models.Subscription:
+					// The account_id of subscription is the publisher it followed, the owner is the follower
+					tx.Delete(model, "follower_id = ?", data.ID)
 				default:
 					tx.Delete(model, "account_id = ?", data.ID)
 				}
 			}
 			tx.Commit()
+
+			if len(followed) > 0 {
+				if _, err := database.RecountPublisherFollowers(database.C, followed...); err != nil {
+					log.Error().Err(err).Msg("An error occurred when recounting followers...")
+				}
+			}
 		case "realm":
 			var data struct {
 				ID int `json:"id"`
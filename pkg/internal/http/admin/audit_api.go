@@ -0,0 +1,106 @@
+package admin
+
+import (
+	"time"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/services"
+	"git.solsynth.dev/hypernet/nexus/pkg/nex/sec"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func listAuditLogs(c *fiber.Ctx) error {
+	if err := sec.EnsureGrantedPerm(c, "ViewAuditLogs", true); err != nil {
+		return err
+	}
+
+	take := c.QueryInt("take", 10)
+	offset := c.QueryInt("offset", 0)
+
+	if take > 100 {
+		take = 100
+	}
+
+	tx := database.C
+	if val := c.QueryInt("actor", 0); val > 0 {
+		tx = tx.Where("actor_id = ?", val)
+	}
+	if val := c.Query("action"); len(val) > 0 {
+		tx = tx.Where("action = ?", val)
+	}
+	if val := c.Query("target_type"); len(val) > 0 {
+		tx = services.FilterAuditLogWithTarget(tx, val, uint(c.QueryInt("target_id", 0)))
+	}
+
+	var err error
+	if tx, err = filterAuditLogWithTime(c, tx); err != nil {
+		return err
+	}
+
+	count, err := services.CountAuditLogs(tx)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	items, err := services.ListAuditLogs(tx, take, offset)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(fiber.Map{
+		"count": count,
+		"data":  items,
+	})
+}
+
+func listPostModerationHistory(c *fiber.Ctx) error {
+	if err := sec.EnsureGrantedPerm(c, "ViewAuditLogs", true); err != nil {
+		return err
+	}
+	id, _ := c.ParamsInt("postId", 0)
+
+	take := c.QueryInt("take", 10)
+	offset := c.QueryInt("offset", 0)
+
+	if take > 100 {
+		take = 100
+	}
+
+	// The post itself isn't required here, the history should be still available after it got deleted
+	tx := services.FilterAuditLogWithTarget(database.C, models.AuditTargetPost, uint(id))
+
+	count, err := services.CountAuditLogs(tx)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	items, err := services.ListAuditLogs(tx, take, offset)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(fiber.Map{
+		"count": count,
+		"data":  items,
+	})
+}
+
+func filterAuditLogWithTime(c *fiber.Ctx, tx *gorm.DB) (*gorm.DB, error) {
+	if val := c.Query("since"); len(val) > 0 {
+		since, err := time.Parse(time.RFC3339, val)
+		if err != nil {
+			return tx, fiber.NewError(fiber.StatusBadRequest, "since must be a RFC3339 timestamp")
+		}
+		tx = tx.Where("created_at >= ?", since)
+	}
+	if val := c.Query("until"); len(val) > 0 {
+		until, err := time.Parse(time.RFC3339, val)
+		if err != nil {
+			return tx, fiber.NewError(fiber.StatusBadRequest, "until must be a RFC3339 timestamp")
+		}
+		tx = tx.Where("created_at <= ?", until)
+	}
+	return tx, nil
+}
@@ -0,0 +1,83 @@
+package admin
+
+import (
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/services"
+	"git.solsynth.dev/hypernet/nexus/pkg/nex/sec"
+	authm "git.solsynth.dev/hypernet/passport/pkg/authkit/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+func listFediverseDeliveries(c *fiber.Ctx) error {
+	if err := sec.EnsureGrantedPerm(c, "ManageFediverse", true); err != nil {
+		return err
+	}
+
+	take := c.QueryInt("take", 10)
+	offset := c.QueryInt("offset", 0)
+	status := c.Query("status", models.FediverseDeliveryDead)
+
+	if take > 100 {
+		take = 100
+	}
+
+	var count int64
+	if err := database.C.Model(&models.FediverseDelivery{}).Where("status = ?", status).Count(&count).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	var items []models.FediverseDelivery
+	if err := database.C.
+		Where("status = ?", status).
+		Order("updated_at DESC").
+		Limit(take).Offset(offset).
+		Preload("Publisher").
+		Find(&items).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(fiber.Map{
+		"count": count,
+		"data":  items,
+	})
+}
+
+func retryFediverseDelivery(c *fiber.Ctx) error {
+	if err := sec.EnsureGrantedPerm(c, "ManageFediverse", true); err != nil {
+		return err
+	}
+	id, _ := c.ParamsInt("deliveryId", 0)
+
+	var item models.FediverseDelivery
+	if err := database.C.Where("id = ?", id).First(&item).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	og := item
+	if item, err := services.RetryActivityPubDelivery(item); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	} else {
+		services.NewAuditLog(c.Locals("user").(authm.Account).ID, "fediverse.deliveries.retry", models.AuditTargetDelivery, item.ID, og, item, "")
+		return c.JSON(item)
+	}
+}
+
+func deleteFediverseDelivery(c *fiber.Ctx) error {
+	if err := sec.EnsureGrantedPerm(c, "ManageFediverse", true); err != nil {
+		return err
+	}
+	id, _ := c.ParamsInt("deliveryId", 0)
+
+	var item models.FediverseDelivery
+	if err := database.C.Where("id = ?", id).First(&item).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	if err := database.C.Unscoped().Delete(&item).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+	services.NewAuditLog(c.Locals("user").(authm.Account).ID, "fediverse.deliveries.delete", models.AuditTargetDelivery, item.ID, item, nil, "")
+
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -3,4 +3,46 @@ package admin
 import "github.com/gofiber/fiber/v2"
 
 func MapControllers(app *fiber.App, baseURL string) {
+	admin := app.Group(baseURL).Name("Admin API")
+	{
+		fediverse := admin.Group("/fediverse").Name("Fediverse Admin API")
+		{
+			fediverse.Get("/deliveries", listFediverseDeliveries)
+			fediverse.Post("/deliveries/:deliveryId/retry", retryFediverseDelivery)
+			fediverse.Delete("/deliveries/:deliveryId", deleteFediverseDelivery)
+		}
+
+		posts := admin.Group("/posts").Name("Posts Admin API")
+		{
+			posts.Get("/", searchPost)
+			posts.Get("/:postId/history", listPostModerationHistory)
+			posts.Delete("/:postId", forceDeletePost)
+			posts.Post("/:postId/lock", lockPost)
+			posts.Post("/:postId/unlock", unlockPost)
+			posts.Post("/:postId/collapse", collapsePost)
+			posts.Post("/:postId/uncollapse", uncollapsePost)
+		}
+
+		admin.Put("/tags/:tagId", editTag)
+		admin.Post("/tags/:tagId/merge", mergeTag)
+		admin.Put("/categories/:categoryId", editCategory)
+		admin.Post("/categories/:categoryId/merge", mergeCategory)
+
+		admin.Post("/publishers/:publisherId/suspend", suspendPublisher)
+		admin.Post("/publishers/:publisherId/unsuspend", unsuspendPublisher)
+
+		admin.Get("/stats", getInstanceStats)
+		admin.Post("/maintenance/recount-votes", recountVoteTotals)
+		admin.Post("/maintenance/recount-followers", recountFollowerTotals)
+
+		admin.Get("/audit-logs", listAuditLogs)
+
+		reports := admin.Group("/reports").Name("Reports Admin API")
+		{
+			reports.Get("/", listReportQueue)
+			reports.Get("/:postId", listPostReports)
+			reports.Post("/:postId/resolve", resolvePostReports)
+			reports.Post("/:postId/dismiss", dismissPostReports)
+		}
+	}
 }
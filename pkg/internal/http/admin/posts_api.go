@@ -0,0 +1,182 @@
+package admin
+
+import (
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/http/exts"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/services"
+	"git.solsynth.dev/hypernet/nexus/pkg/nex/sec"
+	authm "git.solsynth.dev/hypernet/passport/pkg/authkit/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// searchPost lists the posts across all the visibilities, drafts and realms
+func searchPost(c *fiber.Ctx) error {
+	if err := sec.EnsureGrantedPerm(c, "ManagePosts", true); err != nil {
+		return err
+	}
+
+	take := c.QueryInt("take", 10)
+	offset := c.QueryInt("offset", 0)
+
+	if take > 100 {
+		take = 100
+	} else if take < 1 {
+		take = 1
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	tx := database.C
+	if c.QueryBool("deleted") {
+		tx = tx.Unscoped()
+	}
+
+	var order any = "created_at DESC"
+	if probe := c.Query("probe"); len(probe) > 0 {
+		query, err := services.BuildPostSearchQuery(probe)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		tx = services.FilterPostWithFullTextSearch(tx, query)
+		order = services.GetPostSearchOrder(query)
+	}
+	if len(c.Query("author")) > 0 {
+		var author models.Publisher
+		if err := database.C.Where("name = ?", c.Query("author")).First(&author).Error; err != nil {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		tx = tx.Where("publisher_id = ?", author.ID)
+	}
+	if len(c.Query("type")) > 0 {
+		tx = services.FilterPostWithType(tx, c.Query("type"))
+	}
+	if c.QueryBool("collapsed") {
+		tx = tx.Where("is_collapsed = ?", true)
+	}
+	if c.QueryBool("locked") {
+		tx = tx.Where("locked_at IS NOT NULL")
+	}
+
+	count, err := services.CountPost(tx)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	items, err := services.ListPost(tx, take, offset, order, nil)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(fiber.Map{
+		"count": count,
+		"data":  items,
+	})
+}
+
+func getManagedPost(c *fiber.Ctx) (models.Post, error) {
+	id, _ := c.ParamsInt("postId", 0)
+
+	var item models.Post
+	if err := database.C.Where("id = ?", id).Preload("Publisher").First(&item).Error; err != nil {
+		return item, fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	return item, nil
+}
+
+func forceDeletePost(c *fiber.Ctx) error {
+	if err := sec.EnsureGrantedPerm(c, "ManagePosts", true); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+
+	item, err := getManagedPost(c)
+	if err != nil {
+		return err
+	}
+
+	if err := services.DeletePost(item); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+	services.NewAuditLog(user.ID, "posts.delete", models.AuditTargetPost, item.ID, item, nil, c.Query("reason"))
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// updateManagedPost runs the moderator action on the post and records it with the reason in the body
+func updateManagedPost(c *fiber.Ctx, action string, update func(item models.Post, reason string) (models.Post, error)) error {
+	if err := sec.EnsureGrantedPerm(c, "ManagePosts", true); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+
+	var data struct {
+		Reason string `json:"reason" validate:"max=4096"`
+	}
+
+	if len(c.Body()) > 0 {
+		if err := exts.BindAndValidate(c, &data); err != nil {
+			return err
+		}
+	}
+
+	item, err := getManagedPost(c)
+	if err != nil {
+		return err
+	}
+
+	og := item
+	if item, err = update(item, data.Reason); err != nil {
+		return err
+	}
+	services.NewAuditLog(user.ID, action, models.AuditTargetPost, item.ID, og, item, data.Reason)
+
+	return c.JSON(item)
+}
+
+// updateManagedPostState sets the collapse state of the post
+func updateManagedPostState(c *fiber.Ctx, action string, values map[string]any) error {
+	return updateManagedPost(c, action, func(item models.Post, reason string) (models.Post, error) {
+		if err := database.C.Model(&item).Updates(values).Error; err != nil {
+			return item, fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		}
+		return item, nil
+	})
+}
+
+func lockPost(c *fiber.Ctx) error {
+	return updateManagedPost(c, "posts.lock", func(item models.Post, reason string) (models.Post, error) {
+		item, err := services.LockPost(item, reason, true)
+		if err != nil {
+			return item, fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return item, nil
+	})
+}
+
+func unlockPost(c *fiber.Ctx) error {
+	return updateManagedPost(c, "posts.unlock", func(item models.Post, reason string) (models.Post, error) {
+		item, err := services.UnlockPost(item, true)
+		if err != nil {
+			return item, fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return item, nil
+	})
+}
+
+func collapsePost(c *fiber.Ctx) error {
+	return updateManagedPostState(c, "posts.collapse", map[string]any{
+		"is_collapsed":            true,
+		"is_collapsed_by_reports": false,
+		"collapse_review_until":   nil,
+	})
+}
+
+func uncollapsePost(c *fiber.Ctx) error {
+	return updateManagedPostState(c, "posts.uncollapse", map[string]any{
+		"is_collapsed":            false,
+		"is_collapsed_by_reports": false,
+		"collapse_review_until":   nil,
+	})
+}
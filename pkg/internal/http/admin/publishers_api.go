@@ -0,0 +1,63 @@
+package admin
+
+import (
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/http/exts"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/services"
+	"git.solsynth.dev/hypernet/nexus/pkg/nex/sec"
+	authm "git.solsynth.dev/hypernet/passport/pkg/authkit/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+func suspendPublisher(c *fiber.Ctx) error {
+	if err := sec.EnsureGrantedPerm(c, "ManagePublishers", true); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+	id, _ := c.ParamsInt("publisherId", 0)
+
+	var data struct {
+		Reason string `json:"reason" validate:"required,max=4096"`
+	}
+
+	if err := exts.BindAndValidate(c, &data); err != nil {
+		return err
+	}
+
+	var publisher models.Publisher
+	if err := database.C.Where("id = ?", id).First(&publisher).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	og := publisher
+	publisher, err := services.SuspendPublisher(publisher, data.Reason)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	services.NewAuditLog(user.ID, "publishers.suspend", models.AuditTargetPublisher, publisher.ID, og, publisher, data.Reason)
+
+	return c.JSON(publisher)
+}
+
+func unsuspendPublisher(c *fiber.Ctx) error {
+	if err := sec.EnsureGrantedPerm(c, "ManagePublishers", true); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+	id, _ := c.ParamsInt("publisherId", 0)
+
+	var publisher models.Publisher
+	if err := database.C.Where("id = ?", id).First(&publisher).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	og := publisher
+	publisher, err := services.UnsuspendPublisher(publisher)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	services.NewAuditLog(user.ID, "publishers.unsuspend", models.AuditTargetPublisher, publisher.ID, og, publisher, "")
+
+	return c.JSON(publisher)
+}
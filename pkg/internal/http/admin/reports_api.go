@@ -0,0 +1,134 @@
+package admin
+
+import (
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/http/exts"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/services"
+	"git.solsynth.dev/hypernet/nexus/pkg/nex/sec"
+	authm "git.solsynth.dev/hypernet/passport/pkg/authkit/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+func listReportQueue(c *fiber.Ctx) error {
+	if err := sec.EnsureGrantedPerm(c, "ModeratePosts", true); err != nil {
+		return err
+	}
+
+	take := c.QueryInt("take", 10)
+	offset := c.QueryInt("offset", 0)
+	status := c.Query("status", models.PostFlagPending)
+
+	if take > 100 {
+		take = 100
+	}
+
+	count, err := services.CountPostFlagQueue(status)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	items, err := services.ListPostFlagQueue(status, take, offset)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(fiber.Map{
+		"count": count,
+		"data":  items,
+	})
+}
+
+func listPostReports(c *fiber.Ctx) error {
+	if err := sec.EnsureGrantedPerm(c, "ModeratePosts", true); err != nil {
+		return err
+	}
+	id, _ := c.ParamsInt("postId", 0)
+	status := c.Query("status", models.PostFlagPending)
+
+	var item models.Post
+	if err := database.C.Unscoped().Where("id = ?", id).First(&item).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	flags, err := services.ListPostFlags(item, status)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(flags)
+}
+
+func resolvePostReports(c *fiber.Ctx) error {
+	if err := sec.EnsureGrantedPerm(c, "ModeratePosts", true); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+	id, _ := c.ParamsInt("postId", 0)
+
+	var data struct {
+		Outcome string `json:"outcome" validate:"required,oneof=none collapse lock delete"`
+		Note    string `json:"note" validate:"max=4096"`
+	}
+
+	if err := exts.BindAndValidate(c, &data); err != nil {
+		return err
+	}
+
+	// The deleted posts are still listed in the queue, their reports need to be closed too
+	var item models.Post
+	if err := database.C.Unscoped().Where("id = ?", id).Preload("Publisher").First(&item).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	og := item
+	flags, err := services.ResolvePostFlags(item, user.ID, data.Outcome, false)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	services.NewAuditLog(user.ID, "reports.resolve", models.AuditTargetPost, item.ID, og, fiber.Map{
+		"outcome": data.Outcome,
+		"reports": len(flags),
+	}, data.Note)
+
+	return c.JSON(fiber.Map{
+		"count": len(flags),
+	})
+}
+
+func dismissPostReports(c *fiber.Ctx) error {
+	if err := sec.EnsureGrantedPerm(c, "ModeratePosts", true); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+	id, _ := c.ParamsInt("postId", 0)
+
+	var item models.Post
+	if err := database.C.Unscoped().Where("id = ?", id).First(&item).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	var data struct {
+		Note string `json:"note" validate:"max=4096"`
+	}
+
+	if len(c.Body()) > 0 {
+		if err := exts.BindAndValidate(c, &data); err != nil {
+			return err
+		}
+	}
+
+	og := item
+	flags, err := services.ResolvePostFlags(item, user.ID, models.PostFlagOutcomeNone, true)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	services.NewAuditLog(user.ID, "reports.dismiss", models.AuditTargetPost, item.ID, og, fiber.Map{
+		"outcome": models.PostFlagOutcomeNone,
+		"reports": len(flags),
+	}, data.Note)
+
+	return c.JSON(fiber.Map{
+		"count": len(flags),
+	})
+}
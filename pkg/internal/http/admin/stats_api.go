@@ -0,0 +1,62 @@
+package admin
+
+import (
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/services"
+	"git.solsynth.dev/hypernet/nexus/pkg/nex/sec"
+	authm "git.solsynth.dev/hypernet/passport/pkg/authkit/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+func getInstanceStats(c *fiber.Ctx) error {
+	if err := sec.EnsureGrantedPerm(c, "ViewInstanceStats", true); err != nil {
+		return err
+	}
+
+	stats, err := services.GetInstanceStats()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(stats)
+}
+
+func recountVoteTotals(c *fiber.Ctx) error {
+	if err := sec.EnsureGrantedPerm(c, "ManagePosts", true); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+
+	posts, publishers, err := services.RecountVoteTotals()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	result := fiber.Map{
+		"posts":      posts,
+		"publishers": publishers,
+	}
+	services.NewAuditLog(user.ID, "instance.recount_votes", models.AuditTargetInstance, 0, nil, result, "")
+
+	return c.JSON(result)
+}
+
+func recountFollowerTotals(c *fiber.Ctx) error {
+	if err := sec.EnsureGrantedPerm(c, "ManagePublishers", true); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+
+	publishers, err := database.RecountPublisherFollowers(database.C)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	result := fiber.Map{
+		"publishers": publishers,
+	}
+	services.NewAuditLog(user.ID, "instance.recount_followers", models.AuditTargetInstance, 0, nil, result, "")
+
+	return c.JSON(result)
+}
@@ -0,0 +1,142 @@
+package admin
+
+import (
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/http/exts"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/services"
+	"git.solsynth.dev/hypernet/nexus/pkg/nex/sec"
+	authm "git.solsynth.dev/hypernet/passport/pkg/authkit/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+func editTag(c *fiber.Ctx) error {
+	if err := sec.EnsureGrantedPerm(c, "ManageTags", true); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+	id, _ := c.ParamsInt("tagId", 0)
+
+	var data struct {
+		Alias       string `json:"alias" validate:"required,lowercase,max=64"`
+		Name        string `json:"name" validate:"required,max=64"`
+		Description string `json:"description" validate:"max=4096"`
+	}
+
+	if err := exts.BindAndValidate(c, &data); err != nil {
+		return err
+	}
+
+	tag, err := services.GetTagWithID(uint(id))
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	og := tag
+	tag.Alias = data.Alias
+	tag.Name = data.Name
+	tag.Description = data.Description
+
+	if err := database.C.Save(&tag).Error; err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	services.NewAuditLog(user.ID, "tags.edit", models.AuditTargetTag, tag.ID, og, tag, "")
+
+	return c.JSON(tag)
+}
+
+func mergeTag(c *fiber.Ctx) error {
+	if err := sec.EnsureGrantedPerm(c, "ManageTags", true); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+	id, _ := c.ParamsInt("tagId", 0)
+
+	var data struct {
+		Into uint `json:"into" validate:"required"`
+	}
+
+	if err := exts.BindAndValidate(c, &data); err != nil {
+		return err
+	}
+
+	source, err := services.GetTagWithID(uint(id))
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	target, err := services.GetTagWithID(data.Into)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	if err := services.MergeTags(source, target); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	services.NewAuditLog(user.ID, "tags.merge", models.AuditTargetTag, source.ID, source, target, "")
+
+	return c.JSON(target)
+}
+
+func editCategory(c *fiber.Ctx) error {
+	if err := sec.EnsureGrantedPerm(c, "ManageCategories", true); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+	id, _ := c.ParamsInt("categoryId", 0)
+
+	var data struct {
+		Alias       string `json:"alias" validate:"required,lowercase,alphanum,max=64"`
+		Name        string `json:"name" validate:"required,max=64"`
+		Description string `json:"description" validate:"max=4096"`
+	}
+
+	if err := exts.BindAndValidate(c, &data); err != nil {
+		return err
+	}
+
+	category, err := services.GetCategoryWithID(uint(id))
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	og := category
+	category, err = services.EditCategory(category, data.Alias, data.Name, data.Description)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	services.NewAuditLog(user.ID, "categories.edit", models.AuditTargetCategory, category.ID, og, category, "")
+
+	return c.JSON(category)
+}
+
+func mergeCategory(c *fiber.Ctx) error {
+	if err := sec.EnsureGrantedPerm(c, "ManageCategories", true); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+	id, _ := c.ParamsInt("categoryId", 0)
+
+	var data struct {
+		Into uint `json:"into" validate:"required"`
+	}
+
+	if err := exts.BindAndValidate(c, &data); err != nil {
+		return err
+	}
+
+	source, err := services.GetCategoryWithID(uint(id))
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	target, err := services.GetCategoryWithID(data.Into)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	if err := services.MergeCategories(source, target); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	services.NewAuditLog(user.ID, "categories.merge", models.AuditTargetCategory, source.ID, source, target, "")
+
+	return c.JSON(target)
+}
@@ -2,54 +2,84 @@ package api
 
 import (
 	"fmt"
-	"log"
 	"math"
 	"net/http"
-	"strconv"
-	"time"
 
 	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
 	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
 	"git.solsynth.dev/hypernet/interactive/pkg/internal/services"
 	"github.com/go-ap/activitypub"
 	"github.com/gofiber/fiber/v2"
-	"github.com/samber/lo"
+	"github.com/rs/zerolog/log"
 )
 
 func apUserInbox(c *fiber.Ctx) error {
 	name := c.Params("name")
 
-	var activity activitypub.Activity
-	if err := c.BodyParser(&activity); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid activitypub event")
+	var publisher models.Publisher
+	if err := database.C.Where("name = ? AND type != ?", name, models.PublisherTypeFediverse).First(&publisher).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
 	}
 
-	// TODO Handle all these
-	switch activity.Type {
-	case activitypub.LikeType:
-		log.Printf("User %s received a Like on: %s", name, activity.Object.GetID())
-	case activitypub.FollowType:
-		log.Printf("User %s received a Follow request from: %s", name, activity.Actor.GetID())
-	case activitypub.CreateType:
-		log.Printf("New post received for %s: %s", name, activity.Object.GetID())
-	default:
-		log.Printf("Unhandled activity type received: %+v", activity)
+	activity, err := parseApInboxActivity(c, "/users/"+name+"/inbox")
+	if err != nil {
+		return err
+	}
+
+	if err := services.HandleActivityPubInbox(publisher, activity); err != nil {
+		log.Warn().Err(err).Str("type", string(activity.Type)).Str("publisher", name).Msg("An error occurred when handling activity...")
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
 	return c.Status(http.StatusAccepted).SendString("Activity received")
 }
 
-func apUserOutbox(c *fiber.Ctx) error {
-	name := c.Params("name")
-	page := c.QueryInt("page", 1)
-	limit := c.QueryInt("limit", 10)
+// The shared inbox receives the activities for every publisher on this instance
+// The target publisher will be figured out from the activity itself
+func apSharedInbox(c *fiber.Ctx) error {
+	activity, err := parseApInboxActivity(c, "/inbox")
+	if err != nil {
+		return err
+	}
+
+	publisher, _ := services.GetActivityPubTargetPublisher(activity)
+	if err := services.HandleActivityPubInbox(publisher, activity); err != nil {
+		log.Warn().Err(err).Str("type", string(activity.Type)).Msg("An error occurred when handling activity...")
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
 
-	if limit > 100 {
-		limit = 100
+	return c.Status(http.StatusAccepted).SendString("Activity received")
+}
+
+func parseApInboxActivity(c *fiber.Ctx, uri string) (*activitypub.Activity, error) {
+	signer, err := services.VerifyActivityPubRequest(c.Method(), uri, func(key string) string {
+		return c.Get(key)
+	}, c.Body())
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, err.Error())
 	}
 
+	item, err := activitypub.UnmarshalJSON(c.Body())
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "invalid activitypub event")
+	}
+	activity, err := activitypub.ToActivity(item)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "invalid activitypub event")
+	}
+	if activity.Actor == nil || activity.Actor.GetLink() != activitypub.IRI(signer.ID) {
+		return nil, fiber.NewError(fiber.StatusForbidden, "activity actor does not match the signature")
+	}
+
+	return activity, nil
+}
+
+func apUserOutbox(c *fiber.Ctx) error {
+	name := c.Params("name")
+	page, limit := getApCollectionPaging(c)
+
 	var publisher models.Publisher
-	if err := database.C.Where("name = ?", name).First(&publisher).Error; err != nil {
+	if err := database.C.Where("name = ? AND type != ?", name, models.PublisherTypeFediverse).First(&publisher).Error; err != nil {
 		return fiber.NewError(fiber.StatusNotFound, err.Error())
 	}
 
@@ -69,78 +99,105 @@ func apUserOutbox(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
 	} else {
 		for _, post := range posts {
-			var content string
-			if val, ok := post.Body["content"].(string); ok {
-				content = val
-			} else {
-				content = "Posted a post"
-			}
-			note := activitypub.Note{
-				ID:           services.GetActivityID("/posts/" + strconv.Itoa(int(post.ID))),
-				Type:         activitypub.NoteType,
-				Attachment:   nil,
-				AttributedTo: services.GetActivityIRI("/users/" + publisher.Name),
-				Published: lo.TernaryF(post.PublishedAt == nil, func() time.Time {
-					return post.CreatedAt
-				}, func() time.Time {
-					return *post.PublishedAt
-				}),
-				Updated: lo.TernaryF(post.EditedAt == nil, func() time.Time {
-					return post.UpdatedAt
-				}, func() time.Time {
-					return *post.EditedAt
-				}),
-				To:      activitypub.ItemCollection{activitypub.PublicNS},
-				Content: activitypub.DefaultNaturalLanguageValue(content),
-			}
-			activity := activitypub.Create{
-				ID:     services.GetActivityID("/activities/posts/" + strconv.Itoa(int(post.ID))),
-				Type:   activitypub.CreateType,
-				Actor:  services.GetActivityIRI("/users/" + publisher.Name),
-				Object: note,
-			}
-			activities = append(activities, activity)
+			post.Publisher = publisher
+			activities = append(activities, services.BuildActivityPubActivity(post))
 		}
 	}
 
-	totalPages := int(math.Ceil(float64(count) / float64(limit)))
+	return c.JSON(buildApCollectionPage("/users/"+publisher.Name+"/outbox", activities, count, page, limit))
+}
+
+func apUserFollowers(c *fiber.Ctx) error {
+	name := c.Params("name")
+	page, limit := getApCollectionPaging(c)
 
-	outbox := activitypub.OrderedCollectionPage{
-		ID:           services.GetActivityID("/users/" + publisher.Name + "/outbox"),
+	var publisher models.Publisher
+	if err := database.C.Where("name = ? AND type != ?", name, models.PublisherTypeFediverse).First(&publisher).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	count, err := services.CountActivityPubFollowers(publisher)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+	// The publisher hid the followers, only the amount of them is public
+	if !publisher.IsFollowersPublic {
+		return c.JSON(activitypub.OrderedCollection{
+			ID:         services.GetActivityID("/users/" + publisher.Name + "/followers"),
+			Type:       activitypub.OrderedCollectionType,
+			TotalItems: uint(count),
+		})
+	}
+	items, err := services.ListActivityPubFollowers(publisher, limit, (page-1)*limit)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(buildApCollectionPage("/users/"+publisher.Name+"/followers", items, count, page, limit))
+}
+
+func apUserFollowing(c *fiber.Ctx) error {
+	name := c.Params("name")
+	page, limit := getApCollectionPaging(c)
+
+	var publisher models.Publisher
+	if err := database.C.Where("name = ? AND type != ?", name, models.PublisherTypeFediverse).First(&publisher).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	count, err := services.CountActivityPubFollowing(publisher)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+	items, err := services.ListActivityPubFollowing(publisher, limit, (page-1)*limit)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(buildApCollectionPage("/users/"+publisher.Name+"/following", items, count, page, limit))
+}
+
+// getApCollectionPaging reads the page and the limit from the query, the limit is between 1 and 100
+func getApCollectionPaging(c *fiber.Ctx) (page, limit int) {
+	page = max(c.QueryInt("page", 1), 1)
+	limit = min(max(c.QueryInt("limit", 10), 1), 100)
+	return page, limit
+}
+
+func buildApCollectionPage(uri string, items []activitypub.Item, count int64, page, limit int) activitypub.OrderedCollectionPage {
+	totalPages := max(int(math.Ceil(float64(count)/float64(limit))), 1)
+
+	collection := activitypub.OrderedCollectionPage{
+		ID:           services.GetActivityID(uri),
 		Type:         activitypub.OrderedCollectionType,
 		TotalItems:   uint(count),
-		OrderedItems: activitypub.ItemCollection(activities),
-		First:        services.GetActivityIRI(fmt.Sprintf("/users/%s/outbox?page=%d", publisher.Name, 1)),
-		Last:         services.GetActivityIRI(fmt.Sprintf("/users/%s/outbox?page=%d", publisher.Name, totalPages)),
+		OrderedItems: activitypub.ItemCollection(items),
+		First:        services.GetActivityIRI(fmt.Sprintf("%s?page=%d&limit=%d", uri, 1, limit)),
+		Last:         services.GetActivityIRI(fmt.Sprintf("%s?page=%d&limit=%d", uri, totalPages, limit)),
 	}
 
 	if page > 1 {
-		outbox.Prev = services.GetActivityIRI(fmt.Sprintf("/users/%s/outbox?page=%d&limit=%d", publisher.Name, page-1, limit))
+		collection.Prev = services.GetActivityIRI(fmt.Sprintf("%s?page=%d&limit=%d", uri, page-1, limit))
 	}
 	if page < totalPages {
-		outbox.Next = services.GetActivityIRI(fmt.Sprintf("/users/%s/outbox?page=%d&limit=%d", publisher.Name, page+1, limit))
+		collection.Next = services.GetActivityIRI(fmt.Sprintf("%s?page=%d&limit=%d", uri, page+1, limit))
 	}
 
-	return c.JSON(outbox)
+	return collection
 }
 
 func apUserActor(c *fiber.Ctx) error {
 	name := c.Params("name")
 
 	var publisher models.Publisher
-	if err := database.C.Where("name = ?", name).First(&publisher).Error; err != nil {
+	if err := database.C.Where("name = ? AND type != ?", name, models.PublisherTypeFediverse).First(&publisher).Error; err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "User not found"})
 	}
 
-	id := services.GetActivityID("/users/" + publisher.Name)
-	actor := activitypub.Actor{
-		ID:                id,
-		Inbox:             id + "/inbox",
-		Outbox:            id + "/outbox",
-		Type:              activitypub.PersonType,
-		Name:              activitypub.DefaultNaturalLanguageValue(publisher.Name),
-		PreferredUsername: activitypub.DefaultNaturalLanguageValue(publisher.Nick),
+	publisher, err := services.EnsurePublisherKeypair(publisher)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
 	}
 
-	return c.JSON(actor)
+	return c.JSON(services.BuildActivityPubActor(publisher))
 }
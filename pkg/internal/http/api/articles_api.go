@@ -159,6 +159,8 @@ func editArticle(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusForbidden, "post was locked")
 	}
 
+	og := item
+
 	if !item.IsDraft && !data.IsDraft {
 		item.EditedAt = lo.ToPtr(time.Now())
 	}
@@ -181,7 +183,6 @@ func editArticle(c *fiber.Ctx) error {
 	rawBody, _ := jsoniter.Marshal(body)
 	_ = jsoniter.Unmarshal(rawBody, &bodyMapping)
 
-	og := item
 	item.Alias = data.Alias
 	item.Body = bodyMapping
 	item.Language = services.DetectLanguage(data.Content)
@@ -202,7 +203,7 @@ func editArticle(c *fiber.Ctx) error {
 		item.Visibility = *data.Visibility
 	}
 
-	if item, err = services.EditPost(item, og); err != nil {
+	if item, err = services.EditPost(item, og, user.ID); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	} else {
 		_ = authkit.AddEventExt(
@@ -0,0 +1,297 @@
+package api
+
+import (
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/http/exts"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/services"
+	"git.solsynth.dev/hypernet/nexus/pkg/nex/cruda"
+	"git.solsynth.dev/hypernet/nexus/pkg/nex/sec"
+	authm "git.solsynth.dev/hypernet/passport/pkg/authkit/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// getVisiblePost only returns the post when the current user is able to see it
+func getVisiblePost(c *fiber.Ctx, id uint) (models.Post, error) {
+	tx, err := services.UniversalPostFilter(c, database.C, services.UniversalPostFilterConfig{
+		ShowReply:     true,
+		ShowCollapsed: true,
+	})
+	if err != nil {
+		return models.Post{}, err
+	}
+
+	item, err := services.GetPost(tx, id)
+	if err != nil {
+		return item, fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	return item, nil
+}
+
+func bookmarkPost(c *fiber.Ctx) error {
+	if err := sec.EnsureAuthenticated(c); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+	id, _ := c.ParamsInt("postId", 0)
+
+	item, err := getVisiblePost(c, uint(id))
+	if err != nil {
+		return err
+	}
+
+	bookmark, err := services.BookmarkPost(item, user.ID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(bookmark)
+}
+
+func unbookmarkPost(c *fiber.Ctx) error {
+	if err := sec.EnsureAuthenticated(c); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+	id, _ := c.ParamsInt("postId", 0)
+
+	if err := services.UnbookmarkPost(models.Post{BaseModel: cruda.BaseModel{ID: uint(id)}}, user.ID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func listPostCollections(c *fiber.Ctx) error {
+	if err := sec.EnsureAuthenticated(c); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+
+	collections, err := services.ListPostCollections(user.ID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(collections)
+}
+
+func getPostCollection(c *fiber.Ctx) error {
+	id, _ := c.ParamsInt("collectionId", 0)
+
+	var userId *uint
+	if user, authenticated := c.Locals("user").(authm.Account); authenticated {
+		userId = &user.ID
+	}
+
+	collection, err := services.GetPostCollection(uint(id), userId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	return c.JSON(collection)
+}
+
+func listPostCollectionPosts(c *fiber.Ctx) error {
+	take := c.QueryInt("take", 10)
+	offset := c.QueryInt("offset", 0)
+	id, _ := c.ParamsInt("collectionId", 0)
+
+	var userId *uint
+	if user, authenticated := c.Locals("user").(authm.Account); authenticated {
+		userId = &user.ID
+	}
+
+	collection, err := services.GetPostCollection(uint(id), userId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	// The visibility of posts will be checked again, the posts are no longer visible will be hidden
+	tx, err := services.UniversalPostFilter(c, database.C, services.UniversalPostFilterConfig{
+		ShowReply:     true,
+		ShowCollapsed: true,
+	})
+	if err != nil {
+		return err
+	}
+	tx = services.FilterPostWithCollection(tx, collection)
+
+	count, err := services.CountPost(tx)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	items, err := services.ListPost(tx, take, offset, services.GetPostCollectionOrder(collection), userId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(fiber.Map{
+		"count": count,
+		"data":  items,
+	})
+}
+
+func createPostCollection(c *fiber.Ctx) error {
+	if err := sec.EnsureAuthenticated(c); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+
+	var data struct {
+		Name        string `json:"name" validate:"required,max=256"`
+		Description string `json:"description" validate:"max=4096"`
+		IsPublic    bool   `json:"is_public"`
+	}
+
+	if err := exts.BindAndValidate(c, &data); err != nil {
+		return err
+	}
+
+	collection := models.PostCollection{
+		Name:        data.Name,
+		Description: data.Description,
+		IsPublic:    data.IsPublic,
+		AccountID:   user.ID,
+	}
+
+	if err := database.C.Create(&collection).Error; err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(collection)
+}
+
+func editPostCollection(c *fiber.Ctx) error {
+	if err := sec.EnsureAuthenticated(c); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+	id, _ := c.ParamsInt("collectionId", 0)
+
+	var data struct {
+		Name        string `json:"name" validate:"required,max=256"`
+		Description string `json:"description" validate:"max=4096"`
+		IsPublic    bool   `json:"is_public"`
+	}
+
+	if err := exts.BindAndValidate(c, &data); err != nil {
+		return err
+	}
+
+	collection, err := services.GetOwnedPostCollection(uint(id), user.ID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	collection.Name = data.Name
+	collection.Description = data.Description
+	collection.IsPublic = data.IsPublic
+
+	if err := database.C.Save(&collection).Error; err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(collection)
+}
+
+func deletePostCollection(c *fiber.Ctx) error {
+	if err := sec.EnsureAuthenticated(c); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+	id, _ := c.ParamsInt("collectionId", 0)
+
+	collection, err := services.GetOwnedPostCollection(uint(id), user.ID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	if err := services.DeletePostCollection(collection); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func addPostToCollection(c *fiber.Ctx) error {
+	if err := sec.EnsureAuthenticated(c); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+	id, _ := c.ParamsInt("collectionId", 0)
+
+	var data struct {
+		Post uint `json:"post" validate:"required"`
+	}
+
+	if err := exts.BindAndValidate(c, &data); err != nil {
+		return err
+	}
+
+	collection, err := services.GetOwnedPostCollection(uint(id), user.ID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	item, err := getVisiblePost(c, data.Post)
+	if err != nil {
+		return err
+	}
+
+	entry, err := services.AddPostToCollection(collection, item)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(entry)
+}
+
+func removePostFromCollection(c *fiber.Ctx) error {
+	if err := sec.EnsureAuthenticated(c); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+	id, _ := c.ParamsInt("collectionId", 0)
+	postId, _ := c.ParamsInt("postId", 0)
+
+	collection, err := services.GetOwnedPostCollection(uint(id), user.ID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	if err := services.RemovePostFromCollection(collection, uint(postId)); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func reorderPostCollection(c *fiber.Ctx) error {
+	if err := sec.EnsureAuthenticated(c); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+	id, _ := c.ParamsInt("collectionId", 0)
+
+	var data struct {
+		Posts []uint `json:"posts" validate:"required"`
+	}
+
+	if err := exts.BindAndValidate(c, &data); err != nil {
+		return err
+	}
+
+	collection, err := services.GetOwnedPostCollection(uint(id), user.ID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	if err := services.ReorderPostCollection(collection, data.Posts); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -5,6 +5,7 @@ import (
 	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
 	"git.solsynth.dev/hypernet/interactive/pkg/internal/services"
 	"git.solsynth.dev/hypernet/nexus/pkg/nex/sec"
+	authm "git.solsynth.dev/hypernet/passport/pkg/authkit/models"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -61,6 +62,7 @@ func newCategory(c *fiber.Ctx) error {
 	if err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
+	services.NewAuditLog(c.Locals("user").(authm.Account).ID, "categories.create", models.AuditTargetCategory, category.ID, nil, category, "")
 
 	return c.JSON(category)
 }
@@ -86,10 +88,12 @@ func editCategory(c *fiber.Ctx) error {
 		return err
 	}
 
+	og := category
 	category, err = services.EditCategory(category, data.Alias, data.Name, data.Description)
 	if err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
+	services.NewAuditLog(c.Locals("user").(authm.Account).ID, "categories.edit", models.AuditTargetCategory, category.ID, og, category, "")
 
 	return c.JSON(category)
 }
@@ -108,6 +112,7 @@ func deleteCategory(c *fiber.Ctx) error {
 	if err := services.DeleteCategory(category); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
+	services.NewAuditLog(c.Locals("user").(authm.Account).ID, "categories.delete", models.AuditTargetCategory, category.ID, category, nil, "")
 
 	return c.JSON(category)
 }
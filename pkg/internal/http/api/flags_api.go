@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/http/exts"
 	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
 	"git.solsynth.dev/hypernet/interactive/pkg/internal/services"
 	"git.solsynth.dev/hypernet/nexus/pkg/nex/sec"
@@ -18,6 +19,21 @@ func createFlag(c *fiber.Ctx) error {
 	}
 	user := c.Locals("user").(authm.Account)
 
+	var data struct {
+		Reason string `json:"reason" validate:"omitempty,oneof=spam harassment sensitive misinformation illegal other"`
+		Note   string `json:"note" validate:"max=4096"`
+	}
+
+	// The old clients send the flag without the body
+	if len(c.Body()) > 0 {
+		if err := exts.BindAndValidate(c, &data); err != nil {
+			return err
+		}
+	}
+	if len(data.Reason) == 0 {
+		data.Reason = models.PostFlagReasonOther
+	}
+
 	id := c.Params("postId")
 
 	var item models.Post
@@ -41,7 +57,7 @@ func createFlag(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusNotFound, err.Error())
 	}
 
-	flag, err := services.NewFlag(item, user.ID)
+	flag, err := services.NewFlag(item, user.ID, data.Reason, data.Note)
 	if err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
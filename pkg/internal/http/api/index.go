@@ -5,13 +5,25 @@ import (
 )
 
 func MapControllers(app *fiber.App, baseURL string) {
+	// The discovery documents must be served from the root of the host
+	wellKnown := app.Group("/.well-known").Name("Well Known")
+	{
+		wellKnown.Get("/webfinger", getWebfinger)
+		wellKnown.Get("/nodeinfo", getNodeInfoLinks)
+		wellKnown.Get("/host-meta", getHostMeta)
+	}
+
 	api := app.Group(baseURL).Name("API")
 	{
 		api.Get("/webfinger", getWebfinger)
 		activitypub := api.Group("/activitypub").Name("ActivityPub API")
 		{
+			activitypub.Get("/nodeinfo/2.1", getNodeInfo)
+			activitypub.Post("/inbox", apSharedInbox)
 			activitypub.Post("/users/:name/inbox", apUserInbox)
 			activitypub.Get("/users/:name/outbox", apUserOutbox)
+			activitypub.Get("/users/:name/followers", apUserFollowers)
+			activitypub.Get("/users/:name/following", apUserFollowing)
 			activitypub.Get("/users/:name", apUserActor)
 		}
 
@@ -22,6 +34,7 @@ func MapControllers(app *fiber.App, baseURL string) {
 			publishers.Post("/personal", createPersonalPublisher)
 			publishers.Post("/organization", createOrganizationPublisher)
 			publishers.Get("/:name/pins", listPinnedPost)
+			publishers.Get("/:name/followers", listPublisherFollowers)
 			publishers.Get("/:name", getPublisher)
 			publishers.Put("/:name", editPublisher)
 			publishers.Delete("/:name", deletePublisher)
@@ -62,18 +75,44 @@ func MapControllers(app *fiber.App, baseURL string) {
 			posts.Get("/search", searchPost)
 			posts.Get("/minimal", listPostMinimal)
 			posts.Get("/drafts", listDraftPost)
+			posts.Get("/mentioned", listMentionedPost)
+			posts.Get("/scheduled", listScheduledPost)
+			posts.Get("/expired", listExpiredPost)
 			posts.Get("/:postId", getPost)
 			posts.Get("/:postId/insight", getPostInsight)
 			posts.Post("/:postId/flag", createFlag)
 			posts.Post("/:postId/react", reactPost)
 			posts.Post("/:postId/pin", pinPost)
+			posts.Post("/:postId/lock", lockPost)
+			posts.Post("/:postId/unlock", unlockPost)
+			posts.Post("/:postId/bookmark", bookmarkPost)
+			posts.Delete("/:postId/bookmark", unbookmarkPost)
+			posts.Put("/:postId/schedule", reschedulePost)
 			posts.Post("/:postId/uncollapse", uncollapsePost)
 			posts.Delete("/:postId", deletePost)
 
+			posts.Get("/:postId/thread", getPostThread)
+			posts.Get("/:postId/revisions", listPostRevisions)
+			posts.Get("/:postId/revisions/diff", diffPostRevision)
+			posts.Get("/:postId/revisions/:revisionId", getPostRevision)
+			posts.Post("/:postId/revisions/:revisionId/restore", restorePostRevision)
 			posts.Get("/:postId/replies", listPostReplies)
 			posts.Get("/:postId/replies/featured", listPostFeaturedReply)
 		}
 
+		collections := api.Group("/collections").Name("Collections API")
+		{
+			collections.Get("/", listPostCollections)
+			collections.Post("/", createPostCollection)
+			collections.Get("/:collectionId", getPostCollection)
+			collections.Put("/:collectionId", editPostCollection)
+			collections.Delete("/:collectionId", deletePostCollection)
+			collections.Get("/:collectionId/posts", listPostCollectionPosts)
+			collections.Post("/:collectionId/posts", addPostToCollection)
+			collections.Delete("/:collectionId/posts/:postId", removePostFromCollection)
+			collections.Put("/:collectionId/order", reorderPostCollection)
+		}
+
 		polls := api.Group("/polls").Name("Polls API")
 		{
 			polls.Get("/:pollId", getPoll)
@@ -86,6 +125,9 @@ func MapControllers(app *fiber.App, baseURL string) {
 
 		subscriptions := api.Group("/subscriptions").Name("Subscriptions API")
 		{
+			subscriptions.Get("/users", listSubscribedUsers)
+			subscriptions.Get("/tags", listSubscribedTags)
+			subscriptions.Get("/categories", listSubscribedCategories)
 			subscriptions.Get("/users/:userId", getSubscriptionOnUser)
 			subscriptions.Get("/tags/:tagId", getSubscriptionOnTag)
 			subscriptions.Get("/categories/:categoryId", getSubscriptionOnCategory)
@@ -97,6 +139,13 @@ func MapControllers(app *fiber.App, baseURL string) {
 			subscriptions.Delete("/categories/:categoryId", unsubscribeFromCategory)
 		}
 
+		mutes := api.Group("/mutes").Name("Mutes API")
+		{
+			mutes.Get("/", listMutes)
+			mutes.Post("/", createMute)
+			mutes.Delete("/:muteId", deleteMute)
+		}
+
 		api.Get("/categories", listCategories)
 		api.Get("/categories/:category", getCategory)
 		api.Post("/categories", newCategory)
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"time"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/http/exts"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/services"
+	"git.solsynth.dev/hypernet/nexus/pkg/nex/sec"
+	authm "git.solsynth.dev/hypernet/passport/pkg/authkit/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+func listMutes(c *fiber.Ctx) error {
+	if err := sec.EnsureAuthenticated(c); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+
+	mutes, err := services.ListMutes(user.ID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(mutes)
+}
+
+func createMute(c *fiber.Ctx) error {
+	if err := sec.EnsureAuthenticated(c); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+
+	var data struct {
+		Type      string     `json:"type" validate:"required,oneof=publisher tag category keyword"`
+		Publisher *uint      `json:"publisher"`
+		Tag       *uint      `json:"tag"`
+		Category  *uint      `json:"category"`
+		Keyword   *string    `json:"keyword" validate:"omitempty,max=256"`
+		IsRegex   bool       `json:"is_regex"`
+		ExpiredAt *time.Time `json:"expired_at"`
+	}
+
+	if err := exts.BindAndValidate(c, &data); err != nil {
+		return err
+	}
+
+	mute := models.Mute{
+		Type:      data.Type,
+		IsRegex:   data.IsRegex,
+		ExpiredAt: data.ExpiredAt,
+		AccountID: user.ID,
+	}
+
+	switch data.Type {
+	case models.MuteTypePublisher:
+		if data.Publisher == nil {
+			return fiber.NewError(fiber.StatusBadRequest, "publisher mute requires a publisher")
+		}
+		var publisher models.Publisher
+		if err := database.C.Where("id = ?", *data.Publisher).First(&publisher).Error; err != nil {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		mute.PublisherID = &publisher.ID
+	case models.MuteTypeTag:
+		if data.Tag == nil {
+			return fiber.NewError(fiber.StatusBadRequest, "tag mute requires a tag")
+		}
+		tag, err := services.GetTagWithID(*data.Tag)
+		if err != nil {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		mute.TagID = &tag.ID
+	case models.MuteTypeCategory:
+		if data.Category == nil {
+			return fiber.NewError(fiber.StatusBadRequest, "category mute requires a category")
+		}
+		category, err := services.GetCategoryWithID(*data.Category)
+		if err != nil {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		mute.CategoryID = &category.ID
+	case models.MuteTypeKeyword:
+		mute.Keyword = data.Keyword
+	}
+
+	mute, err := services.NewMute(mute)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(mute)
+}
+
+func deleteMute(c *fiber.Ctx) error {
+	if err := sec.EnsureAuthenticated(c); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+	id, _ := c.ParamsInt("muteId", 0)
+
+	mute, err := services.GetMute(uint(id), user.ID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	if err := services.DeleteMute(mute); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
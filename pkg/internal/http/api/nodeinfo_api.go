@@ -0,0 +1,69 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+
+	pkg "git.solsynth.dev/hypernet/interactive/pkg/internal"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/services"
+	"github.com/gofiber/fiber/v2"
+	"github.com/spf13/viper"
+)
+
+const nodeInfoSchema = "http://nodeinfo.diaspora.software/ns/schema/2.1"
+
+func getNodeInfoLinks(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"links": []fiber.Map{
+			{
+				"rel":  nodeInfoSchema,
+				"href": services.GetActivityID("/nodeinfo/2.1").String(),
+			},
+		},
+	})
+}
+
+func getNodeInfo(c *fiber.Ctx) error {
+	usage, err := services.GetNodeInfoUsage()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, fmt.Sprintf(`application/json; profile="%s#"`, nodeInfoSchema))
+	return c.JSON(fiber.Map{
+		"version": "2.1",
+		"software": fiber.Map{
+			"name":       "hypernet-interactive",
+			"version":    pkg.AppVersion,
+			"repository": "https://git.solsynth.dev/hypernet/interactive",
+		},
+		"protocols": []string{"activitypub"},
+		"services": fiber.Map{
+			"inbound":  []string{},
+			"outbound": []string{},
+		},
+		"openRegistrations": viper.GetBool("open_registrations"),
+		"usage": fiber.Map{
+			"users": fiber.Map{
+				"total":          usage.TotalUsers,
+				"activeMonth":    usage.ActiveMonthUsers,
+				"activeHalfyear": usage.ActiveHalfyearUsers,
+			},
+			"localPosts": usage.LocalPosts,
+		},
+		"metadata": fiber.Map{},
+	})
+}
+
+func getHostMeta(c *fiber.Ctx) error {
+	baseUrl, err := url.Parse(viper.GetString("activitypub_base_url"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, "application/xrd+xml; charset=utf-8")
+	return c.SendString(fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<XRD xmlns="http://docs.oasis-open.org/ns/xri/xrd-1.0">
+  <Link rel="lrdd" type="application/jrd+json" template="%s://%s/.well-known/webfinger?resource={uri}"/>
+</XRD>`, baseUrl.Scheme, baseUrl.Host))
+}
@@ -17,6 +17,7 @@ import (
 	"git.solsynth.dev/hypernet/interactive/pkg/internal/services"
 	"git.solsynth.dev/hypernet/interactive/pkg/internal/services/queries"
 	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
 	"github.com/samber/lo"
 )
 
@@ -82,25 +83,53 @@ func searchPost(c *fiber.Ctx) error {
 
 	tx := database.C
 
-	probe := c.Query("probe")
-	if len(probe) == 0 && len(c.Query("tags")) == 0 && len(c.Query("categories")) == 0 {
+	filter, probe, err := services.ParsePostSearchFilter(c.Query("probe"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if len(probe) == 0 && filter.IsEmpty() && len(c.Query("tags")) == 0 && len(c.Query("categories")) == 0 {
 		return fiber.NewError(fiber.StatusBadRequest, "search term (probe, tags or categories) is required")
 	}
+	if tx, err = services.FilterPostWithSearchFilter(tx, filter); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
 
-	tx = services.FilterPostWithFuzzySearch(tx, probe)
+	var userId *uint
+	if user, authenticated := c.Locals("user").(authm.Account); authenticated {
+		userId = &user.ID
+	}
+
+	// Resolve the url or handle from other instances, only for signed-in users to prevent abusing
+	var resolved *models.Publisher
+	isResolved := false
+	if userId != nil && services.IsFediverseProbe(strings.TrimSpace(probe)) {
+		if post, publisher, err := services.ResolveFediverseProbe(strings.TrimSpace(probe)); err != nil {
+			log.Warn().Err(err).Str("probe", probe).Msg("Unable to resolve search term from fediverse...")
+		} else if post != nil {
+			tx = tx.Where("id = ?", post.ID)
+			isResolved = true
+		} else if publisher != nil {
+			tx = tx.Where("publisher_id = ?", publisher.ID)
+			resolved = publisher
+			isResolved = true
+		}
+	}
+	var query *services.PostSearchQuery
+	if !isResolved && len(probe) > 0 {
+		expr, err := services.BuildPostSearchQuery(probe)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		tx = services.FilterPostWithFullTextSearch(tx, expr)
+		query = &expr
+	}
 
-	var err error
 	if tx, err = services.UniversalPostFilter(c, tx, services.UniversalPostFilterConfig{
 		ShowReply: true,
 	}); err != nil {
 		return err
 	}
 
-	var userId *uint
-	if user, authenticated := c.Locals("user").(authm.Account); authenticated {
-		userId = &user.ID
-	}
-
 	var count int64
 	countTx := tx
 	count, err = services.CountPost(countTx)
@@ -108,27 +137,43 @@ func searchPost(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
 	}
 
+	var order any = "published_at DESC"
+	if query != nil {
+		order = services.GetPostSearchOrder(*query)
+	}
+
 	var items []models.Post
 
 	if c.Get("X-API-Version", "1") == "2" {
-		items, err = queries.ListPost(tx, take, offset, "published_at DESC", userId)
+		items, err = queries.ListPost(tx, take, offset, order, userId)
 	} else {
-		items, err = services.ListPost(tx, take, offset, "published_at DESC", userId)
+		items, err = services.ListPost(tx, take, offset, order, userId)
 	}
 	if err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
+	if query != nil {
+		if items, err = services.HighlightPostSearch(items, *query); err != nil {
+			log.Warn().Err(err).Msg("An error occurred when highlighting search results...")
+		}
+	}
+
 	if c.QueryBool("truncate", true) {
 		for _, item := range items {
 			item = services.TruncatePostContent(item)
 		}
 	}
 
-	return c.JSON(fiber.Map{
+	response := fiber.Map{
 		"count": count,
 		"data":  items,
-	})
+	}
+	if resolved != nil {
+		response["publisher"] = resolved
+	}
+
+	return c.JSON(response)
 }
 
 func listPost(c *fiber.Ctx) error {
@@ -213,6 +258,47 @@ func listPostMinimal(c *fiber.Ctx) error {
 	})
 }
 
+// listMentionedPost lists the posts mentioned any publisher of the current user
+// The visibility is still checked, so the posts the user cannot see will not be listed
+func listMentionedPost(c *fiber.Ctx) error {
+	take := c.QueryInt("take", 10)
+	offset := c.QueryInt("offset", 0)
+
+	if err := sec.EnsureAuthenticated(c); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+
+	tx, err := services.UniversalPostFilter(c, database.C, services.UniversalPostFilterConfig{
+		ShowReply: true,
+	})
+	if err != nil {
+		return err
+	}
+	tx = services.FilterPostWithMentioned(tx, user.ID)
+
+	count, err := services.CountPost(tx)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	var items []models.Post
+
+	if c.Get("X-API-Version", "1") == "2" {
+		items, err = queries.ListPost(tx, take, offset, "published_at DESC", &user.ID)
+	} else {
+		items, err = services.ListPost(tx, take, offset, "published_at DESC", &user.ID)
+	}
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(fiber.Map{
+		"count": count,
+		"data":  items,
+	})
+}
+
 func listDraftPost(c *fiber.Ctx) error {
 	take := c.QueryInt("take", 10)
 	offset := c.QueryInt("offset", 0)
@@ -299,6 +385,89 @@ func deletePost(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusOK)
 }
 
+func getOwnedPost(c *fiber.Ctx, user authm.Account) (models.Post, error) {
+	id, _ := c.ParamsInt("postId", 0)
+
+	publisherId := c.QueryInt("publisherId", 0)
+	if publisherId <= 0 {
+		return models.Post{}, fiber.NewError(fiber.StatusBadRequest, "missing publisher id in request")
+	}
+
+	publisher, err := services.GetPublisher(uint(publisherId), user.ID)
+	if err != nil {
+		return models.Post{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var item models.Post
+	if err := database.C.Where(models.Post{
+		BaseModel:   cruda.BaseModel{ID: uint(id)},
+		PublisherID: publisher.ID,
+	}).Preload("Publisher").First(&item).Error; err != nil {
+		return item, fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	return item, nil
+}
+
+func lockPost(c *fiber.Ctx) error {
+	if err := sec.EnsureAuthenticated(c); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+
+	var data struct {
+		Reason string `json:"reason" validate:"max=4096"`
+	}
+
+	if len(c.Body()) > 0 {
+		if err := exts.BindAndValidate(c, &data); err != nil {
+			return err
+		}
+	}
+
+	item, err := getOwnedPost(c, user)
+	if err != nil {
+		return err
+	}
+
+	if item, err = services.LockPost(item, data.Reason, false); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	} else {
+		_ = authkit.AddEventExt(
+			gap.Nx,
+			"posts.lock",
+			map[string]any{"post": item},
+			c,
+		)
+	}
+
+	return c.JSON(item)
+}
+
+func unlockPost(c *fiber.Ctx) error {
+	if err := sec.EnsureAuthenticated(c); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+
+	item, err := getOwnedPost(c, user)
+	if err != nil {
+		return err
+	}
+
+	if item, err = services.UnlockPost(item, false); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	} else {
+		_ = authkit.AddEventExt(
+			gap.Nx,
+			"posts.unlock",
+			map[string]any{"post": item},
+			c,
+		)
+	}
+
+	return c.JSON(item)
+}
+
 func reactPost(c *fiber.Ctx) error {
 	if err := sec.EnsureGrantedPerm(c, "CreateReactions", true); err != nil {
 		return err
@@ -380,9 +549,20 @@ func uncollapsePost(c *fiber.Ctx) error {
 		return err
 	}
 
-	if err := database.C.Model(&models.Post{}).Where("id = ?", id).Update("is_collapsed", false).Error; err != nil {
+	var item models.Post
+	if err := database.C.Where("id = ?", id).First(&item).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	og := item
+	if err := database.C.Model(&item).Updates(map[string]any{
+		"is_collapsed":            false,
+		"is_collapsed_by_reports": false,
+		"collapse_review_until":   nil,
+	}).Error; err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
+	services.NewAuditLog(c.Locals("user").(authm.Account).ID, "posts.uncollapse", models.AuditTargetPost, item.ID, og, item, "")
 
 	return c.SendStatus(fiber.StatusOK)
 }
@@ -52,6 +52,38 @@ func getPublisher(c *fiber.Ctx) error {
 	return c.JSON(publisher)
 }
 
+// listPublisherFollowers is only available for the owner, unless the publisher made the followers list public
+func listPublisherFollowers(c *fiber.Ctx) error {
+	take := c.QueryInt("take", 10)
+	offset := c.QueryInt("offset", 0)
+	name := c.Params("name")
+
+	var publisher models.Publisher
+	if err := database.C.Where("name = ?", name).First(&publisher).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	if !publisher.IsFollowersPublic {
+		if err := sec.EnsureAuthenticated(c); err != nil {
+			return err
+		}
+		user := c.Locals("user").(authm.Account)
+		if publisher.AccountID == nil || *publisher.AccountID != user.ID {
+			return fiber.NewError(fiber.StatusForbidden, "the followers of this publisher are not public")
+		}
+	}
+
+	items, count, err := services.ListPublisherFollowers(publisher, min(take, 100), offset)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(fiber.Map{
+		"count": count,
+		"data":  items,
+	})
+}
+
 func listRelatedPublisher(c *fiber.Ctx) error {
 	tx := database.C
 	if len(c.Query("user")) > 0 {
@@ -185,6 +217,9 @@ func editPublisher(c *fiber.Ctx) error {
 		Avatar      string `json:"avatar"`
 		Banner      string `json:"banner"`
 		AccountID   *uint  `json:"account_id"`
+
+		ExpiredPostAction *int  `json:"expired_post_action" validate:"omitempty,oneof=0 1"`
+		IsFollowersPublic *bool `json:"is_followers_public"`
 	}
 
 	if err := exts.BindAndValidate(c, &data); err != nil {
@@ -200,6 +235,12 @@ func editPublisher(c *fiber.Ctx) error {
 	if data.AccountID != nil {
 		publisher.AccountID = data.AccountID
 	}
+	if data.ExpiredPostAction != nil {
+		publisher.ExpiredPostAction = *data.ExpiredPostAction
+	}
+	if data.IsFollowersPublic != nil {
+		publisher.IsFollowersPublic = *data.IsFollowersPublic
+	}
 
 	if publisher, err = services.EditPublisher(user, publisher, og); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
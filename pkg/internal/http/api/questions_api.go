@@ -204,6 +204,8 @@ func editQuestion(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusForbidden, "post was locked")
 	}
 
+	og := item
+
 	if !item.IsDraft && !data.IsDraft {
 		item.EditedAt = lo.ToPtr(time.Now())
 	}
@@ -234,7 +236,6 @@ func editQuestion(c *fiber.Ctx) error {
 	rawBody, _ := jsoniter.Marshal(newBody)
 	_ = jsoniter.Unmarshal(rawBody, &newBodyMapping)
 
-	og := item
 	item.Alias = data.Alias
 	item.Body = newBodyMapping
 	item.Language = services.DetectLanguage(data.Content)
@@ -255,7 +256,7 @@ func editQuestion(c *fiber.Ctx) error {
 		item.Visibility = *data.Visibility
 	}
 
-	if item, err = services.EditPost(item, og); err != nil {
+	if item, err = services.EditPost(item, og, user.ID); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	} else {
 		_ = authkit.AddEventExt(
@@ -315,11 +316,13 @@ func selectQuestionAnswer(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("related answer was not found: %v", err))
 	}
 
-	item.Body["answer"] = answer.ID
+	// The body is a map, copy it so the original stays untouched
+	og := item
+	item.Body = lo.Assign(item.Body, map[string]any{"answer": answer.ID})
 
 	// Preload publisher data
 	item.Publisher = publisher
-	if item, err = services.EditPost(item, item); err != nil {
+	if item, err = services.EditPost(item, og, user.ID); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	} else {
 		// Give the reward
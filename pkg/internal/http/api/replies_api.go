@@ -97,3 +97,30 @@ func listPostFeaturedReply(c *fiber.Ctx) error {
 
 	return c.JSON(items)
 }
+
+func getPostThread(c *fiber.Ctx) error {
+	depth := c.QueryInt("depth", 3)
+	take := c.QueryInt("take", 50)
+	depth = max(1, min(depth, 8))
+	take = max(1, min(take, 200))
+
+	var post models.Post
+	if err := database.C.Where("id = ?", c.Params("postId")).First(&post).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("unable to find post: %v", err))
+	}
+
+	var userId *uint
+	if user, authenticated := c.Locals("user").(authm.Account); authenticated {
+		userId = &user.ID
+	}
+
+	ancestors, item, err := services.GetPostThread(c, post, depth, take, userId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	return c.JSON(fiber.Map{
+		"ancestors": ancestors,
+		"post":      item,
+	})
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"fmt"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/gap"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/services"
+	"git.solsynth.dev/hypernet/nexus/pkg/nex/sec"
+	"git.solsynth.dev/hypernet/passport/pkg/authkit"
+	authm "git.solsynth.dev/hypernet/passport/pkg/authkit/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// getRevisionPost returns the post only if it is visible for the current user
+func getRevisionPost(c *fiber.Ctx) (models.Post, error) {
+	var item models.Post
+
+	tx, err := services.UniversalPostFilter(c, database.C, services.UniversalPostFilterConfig{
+		ShowReply:     true,
+		ShowCollapsed: true,
+	})
+	if err != nil {
+		return item, err
+	}
+	if err := tx.Where("id = ?", c.Params("postId")).Preload("Publisher").First(&item).Error; err != nil {
+		return item, fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("unable to find post: %v", err))
+	}
+
+	return item, nil
+}
+
+func listPostRevisions(c *fiber.Ctx) error {
+	take := c.QueryInt("take", 10)
+	offset := c.QueryInt("offset", 0)
+
+	item, err := getRevisionPost(c)
+	if err != nil {
+		return err
+	}
+
+	revisions, count, err := services.ListPostRevisions(item, take, offset)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(fiber.Map{
+		"count": count,
+		"data":  revisions,
+	})
+}
+
+func getPostRevision(c *fiber.Ctx) error {
+	id, _ := c.ParamsInt("revisionId", 0)
+
+	item, err := getRevisionPost(c)
+	if err != nil {
+		return err
+	}
+
+	revision, err := services.GetPostRevision(item, uint(id))
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	return c.JSON(revision)
+}
+
+func diffPostRevision(c *fiber.Ctx) error {
+	fromId := c.QueryInt("from", 0)
+	toId := c.QueryInt("to", 0)
+
+	item, err := getRevisionPost(c)
+	if err != nil {
+		return err
+	}
+
+	from, err := services.GetPostRevision(item, uint(fromId))
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("unable to find revision to compare from: %v", err))
+	}
+
+	var to models.PostRevision
+	if toId > 0 {
+		if to, err = services.GetPostRevision(item, uint(toId)); err != nil {
+			return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("unable to find revision to compare to: %v", err))
+		}
+	} else if err := database.C.Where("post_id = ?", item.ID).Order("created_at DESC").First(&to).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("unable to find revision to compare to: %v", err))
+	}
+
+	return c.JSON(services.DiffPostRevision(from, to))
+}
+
+func restorePostRevision(c *fiber.Ctx) error {
+	if err := sec.EnsureAuthenticated(c); err != nil {
+		return err
+	}
+	user := c.Locals("user").(authm.Account)
+	id, _ := c.ParamsInt("revisionId", 0)
+
+	var item models.Post
+	if err := database.C.Where("id = ?", c.Params("postId")).Preload("Publisher").First(&item).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	if _, err := services.GetPublisher(item.PublisherID, user.ID); err != nil {
+		return fiber.NewError(fiber.StatusForbidden, err.Error())
+	}
+
+	revision, err := services.GetPostRevision(item, uint(id))
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	if item, err = services.RestorePostRevision(item, revision, user.ID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	} else {
+		_ = authkit.AddEventExt(
+			gap.Nx,
+			"posts.edit",
+			map[string]interface{}{"post": item},
+			c,
+		)
+	}
+
+	return c.JSON(item)
+}
@@ -185,6 +185,8 @@ func editStory(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusForbidden, "post was locked")
 	}
 
+	og := item
+
 	if !item.IsDraft && !data.IsDraft {
 		item.EditedAt = lo.ToPtr(time.Now())
 	}
@@ -207,7 +209,6 @@ func editStory(c *fiber.Ctx) error {
 	rawBody, _ := jsoniter.Marshal(body)
 	_ = jsoniter.Unmarshal(rawBody, &bodyMapping)
 
-	og := item
 	item.Alias = data.Alias
 	item.Body = bodyMapping
 	item.Language = services.DetectLanguage(data.Content)
@@ -229,7 +230,7 @@ func editStory(c *fiber.Ctx) error {
 		item.Visibility = *data.Visibility
 	}
 
-	if item, err = services.EditPost(item, og); err != nil {
+	if item, err = services.EditPost(item, og, user.ID); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	} else {
 		_ = authkit.AddEventExt(
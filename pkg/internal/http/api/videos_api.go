@@ -167,6 +167,8 @@ func editVideo(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusForbidden, "post was locked")
 	}
 
+	og := item
+
 	if !item.IsDraft && !data.IsDraft {
 		item.EditedAt = lo.ToPtr(time.Now())
 	}
@@ -192,7 +194,6 @@ func editVideo(c *fiber.Ctx) error {
 	rawBody, _ := jsoniter.Marshal(body)
 	_ = jsoniter.Unmarshal(rawBody, &bodyMapping)
 
-	og := item
 	item.Alias = data.Alias
 	item.Body = bodyMapping
 	item.Language = services.DetectLanguage(data.Title)
@@ -213,7 +214,7 @@ func editVideo(c *fiber.Ctx) error {
 		item.Visibility = *data.Visibility
 	}
 
-	if item, err = services.EditPost(item, og); err != nil {
+	if item, err = services.EditPost(item, og, user.ID); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	} else {
 		_ = authkit.AddEventExt(
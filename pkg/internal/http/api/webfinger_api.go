@@ -9,14 +9,16 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
+type WebFingerLink struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type,omitempty"`
+	Href string `json:"href"`
+}
+
 type WebFingerResponse struct {
-	Subject string   `json:"subject"`
-	Aliases []string `json:"aliases"`
-	Links   []struct {
-		Rel  string `json:"rel"`
-		Type string `json:"type,omitempty"`
-		Href string `json:"href"`
-	} `json:"links"`
+	Subject string          `json:"subject"`
+	Aliases []string        `json:"aliases"`
+	Links   []WebFingerLink `json:"links"`
 }
 
 // Although this webfinger is desgined for users
@@ -38,7 +40,7 @@ func getWebfinger(c *fiber.Ctx) error {
 	}
 
 	var publisher models.Publisher
-	if err := database.C.Where("name = ?", parts[0]).First(&publisher).Error; err != nil {
+	if err := database.C.Where("name = ? AND type != ?", parts[0], models.PublisherTypeFediverse).First(&publisher).Error; err != nil {
 		return fiber.NewError(fiber.StatusNotFound, err.Error())
 	}
 
@@ -46,20 +48,27 @@ func getWebfinger(c *fiber.Ctx) error {
 		Subject: "acct:" + username,
 		Aliases: []string{
 			services.GetActivityID("/users/" + publisher.Name).String(),
+			services.GetPublisherPageUrl(publisher),
 		},
-		Links: []struct {
-			Rel  string `json:"rel"`
-			Type string `json:"type,omitempty"`
-			Href string `json:"href"`
-		}{
+		Links: []WebFingerLink{
 			{
 				Rel:  "self",
-				Type: "application/activity+json",
+				Type: services.ActivityPubContentType,
 				Href: services.GetActivityID("/users/" + publisher.Name).String(),
 			},
-			// TODO Add avatar here
+			{
+				Rel:  "http://webfinger.net/rel/profile-page",
+				Type: "text/html",
+				Href: services.GetPublisherPageUrl(publisher),
+			},
 		},
 	}
+	if len(publisher.Avatar) > 0 {
+		response.Links = append(response.Links, WebFingerLink{
+			Rel:  "http://webfinger.net/rel/avatar",
+			Href: services.GetAttachmentUrl(publisher.Avatar),
+		})
+	}
 
 	return c.JSON(response)
 }
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"git.solsynth.dev/hypernet/nexus/pkg/nex/cruda"
+	"gorm.io/datatypes"
+)
+
+const (
+	AuditTargetPost      = "post"
+	AuditTargetPublisher = "publisher"
+	AuditTargetTag       = "tag"
+	AuditTargetCategory  = "category"
+	AuditTargetInstance  = "instance"
+	AuditTargetDelivery  = "fediverse_delivery"
+)
+
+// AuditLog records the privileged actions, the before and after are the snapshots of the target
+type AuditLog struct {
+	cruda.BaseModel
+
+	Action     string         `json:"action" gorm:"index"`
+	TargetType string         `json:"target_type" gorm:"index:idx_audit_log_target"`
+	TargetID   uint           `json:"target_id" gorm:"index:idx_audit_log_target"`
+	Before     datatypes.JSON `json:"before"`
+	After      datatypes.JSON `json:"after"`
+	Reason     string         `json:"reason"`
+	ActorID    uint           `json:"actor_id" gorm:"index"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "git.solsynth.dev/hypernet/nexus/pkg/nex/cruda"
+
+// PostBookmark is a post saved by the user for later
+type PostBookmark struct {
+	cruda.BaseModel
+
+	PostID    uint `json:"post_id" gorm:"uniqueIndex:idx_post_bookmark"`
+	Post      Post `json:"post"`
+	AccountID uint `json:"account_id" gorm:"uniqueIndex:idx_post_bookmark"`
+}
+
+// PostCollection is a named and ordered list of posts created by the user
+type PostCollection struct {
+	cruda.BaseModel
+
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	IsPublic    bool                 `json:"is_public"`
+	Items       []PostCollectionItem `json:"items" gorm:"foreignKey:CollectionID"`
+	AccountID   uint                 `json:"account_id"`
+}
+
+type PostCollectionItem struct {
+	cruda.BaseModel
+
+	Position     int  `json:"position"`
+	PostID       uint `json:"post_id" gorm:"uniqueIndex:idx_post_collection_item"`
+	Post         Post `json:"post"`
+	CollectionID uint `json:"collection_id" gorm:"uniqueIndex:idx_post_collection_item"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"git.solsynth.dev/hypernet/nexus/pkg/nex/cruda"
+	"gorm.io/datatypes"
+)
+
+type FediverseFollower struct {
+	cruda.BaseModel
+
+	ActorID     string `json:"actor_id" gorm:"uniqueIndex:idx_fediverse_follower"`
+	Inbox       string `json:"inbox"`
+	SharedInbox string `json:"shared_inbox"`
+	// FollowID is the IRI of the Follow activity, the Undo may only refer to it by link
+	FollowID string `json:"follow_id" gorm:"index"`
+
+	PublisherID uint      `json:"publisher_id" gorm:"uniqueIndex:idx_fediverse_follower"`
+	Publisher   Publisher `json:"publisher"`
+}
+
+// FediverseFriendCursor keeps the newest status pulled from a fediverse friend
+// So the timeline won't be fetched from the beginning again after restarting
+type FediverseFriendCursor struct {
+	cruda.BaseModel
+
+	FriendID string `json:"friend_id" gorm:"uniqueIndex"`
+	Cursor   string `json:"cursor"`
+}
+
+const (
+	FediverseDeliveryPending = "pending"
+	FediverseDeliveryDead    = "dead"
+)
+
+// FediverseDelivery is an activity waiting to be sent to a remote inbox
+// The delivered ones will be removed, the ones that failed too many times will stay as dead
+type FediverseDelivery struct {
+	cruda.BaseModel
+
+	Inbox         string         `json:"inbox"`
+	Activity      datatypes.JSON `json:"activity"`
+	Status        string         `json:"status" gorm:"index"`
+	Attempts      int            `json:"attempts"`
+	LastError     string         `json:"last_error"`
+	NextAttemptAt time.Time      `json:"next_attempt_at" gorm:"index"`
+
+	PublisherID uint      `json:"publisher_id"`
+	Publisher   Publisher `json:"publisher"`
+}
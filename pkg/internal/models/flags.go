@@ -1,10 +1,46 @@
 package models
 
-import "git.solsynth.dev/hypernet/nexus/pkg/nex/cruda"
+import (
+	"time"
 
+	"git.solsynth.dev/hypernet/nexus/pkg/nex/cruda"
+)
+
+const (
+	PostFlagReasonSpam           = "spam"
+	PostFlagReasonHarassment     = "harassment"
+	PostFlagReasonSensitive      = "sensitive"
+	PostFlagReasonMisinformation = "misinformation"
+	PostFlagReasonIllegal        = "illegal"
+	PostFlagReasonOther          = "other"
+)
+
+const (
+	PostFlagPending   = "pending"
+	PostFlagResolved  = "resolved"
+	PostFlagDismissed = "dismissed"
+)
+
+// What the moderator did to the post when resolving the reports
+const (
+	PostFlagOutcomeNone     = "none"
+	PostFlagOutcomeCollapse = "collapse"
+	PostFlagOutcomeLock     = "lock"
+	PostFlagOutcomeDelete   = "delete"
+)
+
+// PostFlag is a report of the post, the reports of the same post are reviewed together by the moderator
 type PostFlag struct {
 	cruda.BaseModel
 
+	Reason string `json:"reason" gorm:"default:other"`
+	Note   string `json:"note"`
+	Status string `json:"status" gorm:"index;default:pending"`
+
+	ModeratorID *uint      `json:"moderator_id"`
+	Outcome     *string    `json:"outcome"`
+	ResolvedAt  *time.Time `json:"resolved_at"`
+
 	PostID    uint `json:"post_id"`
 	AccountID uint `json:"account_id"`
 }
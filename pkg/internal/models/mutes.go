@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"git.solsynth.dev/hypernet/nexus/pkg/nex/cruda"
+)
+
+const (
+	MuteTypePublisher = "publisher"
+	MuteTypeTag       = "tag"
+	MuteTypeCategory  = "category"
+	MuteTypeKeyword   = "keyword"
+)
+
+// Mute hides the posts from the user's view without changing the relationship
+// The keyword mute can be a plain text or a regular expression when IsRegex is true
+type Mute struct {
+	cruda.BaseModel
+
+	Type        string     `json:"type"`
+	PublisherID *uint      `json:"publisher_id,omitempty"`
+	Publisher   *Publisher `json:"publisher,omitempty"`
+	TagID       *uint      `json:"tag_id,omitempty"`
+	Tag         *Tag       `json:"tag,omitempty"`
+	CategoryID  *uint      `json:"category_id,omitempty"`
+	Category    *Category  `json:"category,omitempty"`
+	Keyword     *string    `json:"keyword,omitempty"`
+	IsRegex     bool       `json:"is_regex"`
+	ExpiredAt   *time.Time `json:"expired_at"`
+	AccountID   uint       `json:"account_id" gorm:"index"`
+}
@@ -36,6 +36,7 @@ type Post struct {
 	AliasPrefix *string           `json:"alias_prefix" gorm:"index"`
 	Tags        []Tag             `json:"tags" gorm:"many2many:post_tags"`
 	Categories  []Category        `json:"categories" gorm:"many2many:post_categories"`
+	Mentions    []Publisher       `json:"mentions" gorm:"many2many:post_mentions"`
 	Reactions   []Reaction        `json:"reactions"`
 	Replies     []Post            `json:"replies" gorm:"foreignKey:ReplyID"`
 	Flags       []PostFlag        `json:"flags" gorm:"foreignKey:PostID"`
@@ -52,11 +53,30 @@ type Post struct {
 	PinnedAt *time.Time `json:"pinned_at"`
 	LockedAt *time.Time `json:"locked_at"`
 
+	// LockReason tells the others why the post was closed, LockedByModerator prevents the author from reopening it
+	LockReason        string `json:"lock_reason"`
+	LockedByModerator bool   `json:"locked_by_moderator"`
+
 	IsCollapsed    bool       `json:"is_collapsed"`
 	IsDraft        bool       `json:"is_draft"`
 	PublishedAt    *time.Time `json:"published_at"`
 	PublishedUntil *time.Time `json:"published_until"`
 
+	// ExpiredAt is set when the post was archived after PublishedUntil
+	ExpiredAt *time.Time `json:"expired_at"`
+
+	// IsCollapsedByReports tells the collapse was caused by the reports instead of the moderator
+	// CollapseReviewUntil is set when the reports collapsed the post, it will be restored if nobody reviewed it before then
+	// CollapseRestoredAt is when the review expired, only the reports after it can collapse the post again
+	IsCollapsedByReports bool       `json:"is_collapsed_by_reports"`
+	CollapseReviewUntil  *time.Time `json:"collapse_review_until"`
+	CollapseRestoredAt   *time.Time `json:"collapse_restored_at"`
+
+	// IsScheduled means the post is waiting for its PublishedAt to notify the others
+	// IsRescheduled means it was published before, so it will only be federated again without notifying
+	IsScheduled   bool `json:"is_scheduled" gorm:"index"`
+	IsRescheduled bool `json:"is_rescheduled"`
+
 	TotalUpvote          int   `json:"total_upvote"`
 	TotalDownvote        int   `json:"total_downvote"`
 	TotalViews           int64 `json:"total_views"`
@@ -71,7 +91,13 @@ type Post struct {
 	PublisherID uint      `json:"publisher_id"`
 	Publisher   Publisher `json:"publisher"`
 
+	// FediverseID is the object IRI of a post that came from another instance
+	FediverseID *string `json:"fediverse_id" gorm:"uniqueIndex"`
+
 	Metric PostMetric `json:"metric" gorm:"-"`
+
+	// Highlight is the snippet of the matched words, only available in the search results
+	Highlight string `json:"highlight,omitempty" gorm:"-"`
 }
 
 type PostStoryBody struct {
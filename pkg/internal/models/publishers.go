@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"git.solsynth.dev/hypernet/nexus/pkg/nex/cruda"
 	"git.solsynth.dev/hypernet/passport/pkg/authkit/models"
 )
@@ -9,6 +11,13 @@ const (
 	PublisherTypePersonal = iota
 	PublisherTypeOrganization
 	PublisherTypeAnonymous
+	PublisherTypeFediverse
+)
+
+// What to do with the posts after their PublishedUntil
+const (
+	ExpiredPostArchive = iota
+	ExpiredPostDelete
 )
 
 type Publisher struct {
@@ -24,12 +33,30 @@ type Publisher struct {
 
 	Posts []Post `json:"posts"`
 
-	TotalUpvote   int `json:"total_upvote"`
-	TotalDownvote int `json:"total_downvote"`
+	TotalUpvote    int `json:"total_upvote"`
+	TotalDownvote  int `json:"total_downvote"`
+	TotalFollowers int `json:"total_followers"`
+
+	// IsFollowersPublic allows everyone to see the followers list, otherwise only the owner can see it
+	IsFollowersPublic bool `json:"is_followers_public"`
 
 	RealmID   *uint `json:"realm_id"`
 	AccountID *uint `json:"account_id"`
 
+	ExpiredPostAction int `json:"expired_post_action"`
+
+	// The suspended publisher cannot create or edit posts until the admin lifts it
+	SuspendedAt   *time.Time `json:"suspended_at"`
+	SuspendReason string     `json:"suspend_reason"`
+
+	// FediverseID is the actor IRI of a remote publisher, only set when the type is PublisherTypeFediverse
+	FediverseID *string `json:"fediverse_id" gorm:"uniqueIndex"`
+
+	// The keypair used to sign the ActivityPub requests, in PEM format
+	// It will be generated when the publisher is federated for the first time
+	PublicKey  string `json:"-"`
+	PrivateKey string `json:"-"`
+
 	Account models.Account `gorm:"-" json:"account"`
 	Realm   models.Realm   `gorm:"-" json:"realm"`
 }
@@ -22,4 +22,9 @@ type Reaction struct {
 
 	PostID    uint `json:"post_id"`
 	AccountID uint `json:"account_id"`
+
+	// FediverseID is the IRI of the Like activity when the reaction came from a remote actor
+	// FediverseActor is the IRI of that actor, only it can undo the reaction
+	FediverseID    *string `json:"fediverse_id" gorm:"uniqueIndex"`
+	FediverseActor *string `json:"fediverse_actor"`
 }
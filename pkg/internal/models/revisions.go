@@ -0,0 +1,22 @@
+package models
+
+import (
+	"git.solsynth.dev/hypernet/nexus/pkg/nex/cruda"
+	"gorm.io/datatypes"
+)
+
+// PostRevision is a snapshot of a post after it was edited
+// The first revision of a post is the state before its first edit
+type PostRevision struct {
+	cruda.BaseModel
+
+	Body       datatypes.JSONMap           `json:"body"`
+	Tags       datatypes.JSONSlice[string] `json:"tags"`
+	Categories datatypes.JSONSlice[string] `json:"categories"`
+	Visibility PostVisibilityLevel         `json:"visibility"`
+
+	// EditorID is the id of the account who made this revision
+	EditorID *uint `json:"editor_id"`
+
+	PostID uint `json:"post_id" gorm:"index"`
+}
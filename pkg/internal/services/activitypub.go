@@ -1,10 +1,29 @@
 package services
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/gap"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"git.solsynth.dev/hypernet/nexus/pkg/nex/cachekit"
 	"github.com/go-ap/activitypub"
+	"github.com/goccy/go-json"
 	"github.com/spf13/viper"
+	"gorm.io/gorm"
 )
 
+const ActivityPubContentType = "application/activity+json"
+
 func GetActivityID(uri string) activitypub.ID {
 	baseUrl := viper.GetString("activitypub_base_url")
 	return activitypub.ID(baseUrl + uri)
@@ -14,3 +33,262 @@ func GetActivityIRI(uri string) activitypub.IRI {
 	baseUrl := viper.GetString("activitypub_base_url")
 	return activitypub.IRI(baseUrl + uri)
 }
+
+// ParseActivityPostID will try to get the local post id from an object IRI
+// It returns false when the IRI is not belongs to this instance
+func ParseActivityPostID(iri string) (uint, bool) {
+	prefix := viper.GetString("activitypub_base_url") + "/posts/"
+	if !strings.HasPrefix(iri, prefix) {
+		return 0, false
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(iri, prefix))
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// ParseActivityPubPublisherName will try to get the local publisher name from an actor IRI
+func ParseActivityPubPublisherName(iri string) (string, bool) {
+	prefix := viper.GetString("activitypub_base_url") + "/users/"
+	if !strings.HasPrefix(iri, prefix) {
+		return "", false
+	}
+	name := strings.TrimPrefix(iri, prefix)
+	if len(name) == 0 || strings.Contains(name, "/") {
+		return "", false
+	}
+	return name, true
+}
+
+// GetActivityPubTargetPublisher figures out which local publisher the activity is sent to
+// It is used by the shared inbox, only the follow related activities have a publisher as the target
+func GetActivityPubTargetPublisher(activity *activitypub.Activity) (models.Publisher, error) {
+	var publisher models.Publisher
+
+	object := activity.Object
+	if activity.Type == activitypub.UndoType && object != nil && object.IsLink() {
+		// Only the link of the undone activity is given, it can be a Follow we have stored
+		var follower models.FediverseFollower
+		err := database.C.
+			Where("follow_id = ? AND actor_id = ?", object.GetLink().String(), activity.Actor.GetLink().String()).
+			Preload("Publisher").
+			First(&follower).Error
+		return follower.Publisher, err
+	}
+	if activity.Type == activitypub.UndoType && object != nil {
+		_ = activitypub.OnActivity(object, func(inner *activitypub.Activity) error {
+			object = inner.Object
+			return nil
+		})
+	}
+	if object == nil {
+		return publisher, fmt.Errorf("activity has no object")
+	}
+
+	name, ok := ParseActivityPubPublisherName(object.GetLink().String())
+	if !ok {
+		return publisher, fmt.Errorf("activity is not sent to any publisher")
+	}
+	err := database.C.Where("name = ? AND type != ?", name, models.PublisherTypeFediverse).First(&publisher).Error
+	return publisher, err
+}
+
+// GetAttachmentUrl returns the public url of an attachment
+// The value which is already an url will be returned as is
+func GetAttachmentUrl(rid string) string {
+	if strings.HasPrefix(rid, "http") {
+		return rid
+	}
+	return viper.GetString("attachment_base_url") + "/" + rid
+}
+
+func GetPublisherPageUrl(publisher models.Publisher) string {
+	return viper.GetString("web_base_url") + "/publishers/" + publisher.Name
+}
+
+// MarshalActivityPub encodes the item with the ActivityStreams context
+// The context is required by most of the implementations but the library won't add it
+func MarshalActivityPub(item activitypub.Item) ([]byte, error) {
+	raw, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	var data map[string]any
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+	data["@context"] = activitypub.ActivityBaseURI
+	return json.Marshal(data)
+}
+
+func FetchActivityPubObject(iri string) (activitypub.Item, error) {
+	raw, err := fetchActivityPubRaw(iri)
+	if err != nil {
+		return nil, err
+	}
+	return activitypub.UnmarshalJSON(raw)
+}
+
+// FetchActivityPubActor will fetch the remote actor profile
+// The result will be cached for a while, pass noCache to force refresh it
+func FetchActivityPubActor(iri string, noCache ...bool) (*activitypub.Actor, error) {
+	cacheKey := fmt.Sprintf("activitypub-actor#%s", iri)
+
+	var raw []byte
+	if len(noCache) == 0 || !noCache[0] {
+		if cached, err := cachekit.Get[string](gap.Ca, cacheKey); err == nil && len(cached) > 0 {
+			raw = []byte(cached)
+		}
+	}
+	if raw == nil {
+		var err error
+		if raw, err = fetchActivityPubRaw(iri); err != nil {
+			return nil, err
+		}
+		cachekit.Set[string](gap.Ca, cacheKey, string(raw), 30*time.Minute)
+	}
+
+	item, err := activitypub.UnmarshalJSON(raw)
+	if err != nil {
+		return nil, err
+	}
+	return activitypub.ToActor(item)
+}
+
+func fetchActivityPubRaw(iri string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, iri, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateActivityPubURL(req.URL); err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %v", iri, err)
+	}
+	req.Header.Set("Accept", ActivityPubContentType)
+
+	resp, err := activityPubClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %v", iri, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: status code %d", iri, resp.StatusCode)
+	}
+
+	return readActivityPubBody(resp.Body)
+}
+
+func DeliverActivityPubActivity(publisher models.Publisher, inbox string, activity activitypub.Item) error {
+	raw, err := MarshalActivityPub(activity)
+	if err != nil {
+		return err
+	}
+	return deliverActivityPubRaw(publisher, inbox, raw)
+}
+
+func deliverActivityPubRaw(publisher models.Publisher, inbox string, raw []byte) error {
+	req, err := http.NewRequest(http.MethodPost, inbox, bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	if err := ValidateActivityPubURL(req.URL); err != nil {
+		return fmt.Errorf("failed to deliver activity to %s: %v", inbox, err)
+	}
+	req.Header.Set("Content-Type", ActivityPubContentType)
+	req.Header.Set("Accept", ActivityPubContentType)
+	if err := SignActivityPubRequest(publisher, req, raw); err != nil {
+		return err
+	}
+
+	resp, err := activityPubClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver activity to %s: %v", inbox, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to deliver activity to %s: status code %d", inbox, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// GetFediversePublisher will return the publisher record of a remote actor
+// If the publisher record does not exist, it will be created by using the actor profile
+func GetFediversePublisher(actor *activitypub.Actor) (models.Publisher, error) {
+	id := actor.ID.String()
+	uri, err := url.Parse(id)
+	if err != nil {
+		return models.Publisher{}, fmt.Errorf("invalid actor id: %v", err)
+	}
+
+	username := actor.PreferredUsername.String()
+	if len(username) == 0 {
+		username = strings.TrimPrefix(uri.Path[strings.LastIndex(uri.Path, "/"):], "/")
+	}
+	nick := actor.Name.String()
+	if len(nick) == 0 {
+		nick = username
+	}
+
+	return EnsureFediversePublisher(models.Publisher{
+		Name:        fmt.Sprintf("%s@%s", username, uri.Host),
+		Nick:        nick,
+		Description: ConvertActivityPubContent(actor.Summary.String()),
+		Avatar:      getActivityPubItemUrl(actor.Icon),
+		Banner:      getActivityPubItemUrl(actor.Image),
+		FediverseID: &id,
+	})
+}
+
+// EnsureFediversePublisher returns the existing publisher with the same fediverse id
+// Or create one with the provided profile
+func EnsureFediversePublisher(publisher models.Publisher) (models.Publisher, error) {
+	if publisher.FediverseID == nil {
+		return publisher, fmt.Errorf("remote publisher must have a fediverse id")
+	}
+
+	var existing models.Publisher
+	if err := database.C.Where("fediverse_id = ?", *publisher.FediverseID).First(&existing).Error; err == nil {
+		return existing, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return existing, err
+	}
+
+	publisher.Type = models.PublisherTypeFediverse
+	if err := database.C.Create(&publisher).Error; err != nil {
+		return publisher, err
+	}
+
+	return publisher, nil
+}
+
+func getActivityPubItemUrl(item activitypub.Item) string {
+	if item == nil {
+		return ""
+	}
+	if item.IsLink() {
+		return item.GetLink().String()
+	}
+	var out string
+	_ = activitypub.OnObject(item, func(object *activitypub.Object) error {
+		if object.URL != nil {
+			out = object.URL.GetLink().String()
+		}
+		return nil
+	})
+	return out
+}
+
+var (
+	activityPubLineBreakRegex = regexp.MustCompile(`(?i)<br\s*/?>`)
+	activityPubParagraphRegex = regexp.MustCompile(`(?i)</p>\s*<p[^>]*>`)
+	activityPubTagRegex       = regexp.MustCompile(`<[^>]+>`)
+)
+
+// ConvertActivityPubContent turns the HTML content from other instances into the plain text
+func ConvertActivityPubContent(content string) string {
+	content = activityPubLineBreakRegex.ReplaceAllString(content, "\n")
+	content = activityPubParagraphRegex.ReplaceAllString(content, "\n\n")
+	content = activityPubTagRegex.ReplaceAllString(content, "")
+	return strings.TrimSpace(html.UnescapeString(content))
+}
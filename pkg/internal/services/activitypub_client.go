@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// activityPubMaxResponseSize caps the body read from other instances, the objects and actors are far smaller than this
+const activityPubMaxResponseSize = 4 << 20
+
+// activityPubClient is used for every request to the urls came from the users or other instances
+// The address is checked when dialing, so the redirects and the DNS rebinding can't reach the internal network either
+var activityPubClient = newActivityPubClient()
+
+// activityPubBlockedNets are the special ranges not covered by the net.IP helpers
+var activityPubBlockedNets = parseCIDRs(
+	"0.0.0.0/8",
+	"100.64.0.0/10",
+	"192.0.0.0/24",
+	"198.18.0.0/15",
+	"240.0.0.0/4",
+	"64:ff9b::/96",
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	var out []*net.IPNet
+	for _, cidr := range cidrs {
+		_, block, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		out = append(out, block)
+	}
+	return out
+}
+
+func newActivityPubClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// The proxy will be the one we dial to, the destination cannot be checked through it
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if len(ips) == 0 {
+			return nil, fmt.Errorf("%s has no address", host)
+		}
+		for _, ip := range ips {
+			if !IsPublicIP(ip.IP) {
+				return nil, fmt.Errorf("%s resolves to a non-public address", host)
+			}
+		}
+		// Dial the checked address directly, resolving again may give another answer
+		return dialer.DialContext(ctx, network, net.JoinHostPort(ips[0].IP.String(), port))
+	}
+
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return fmt.Errorf("stopped after 5 redirects")
+			}
+			return ValidateActivityPubURL(req.URL)
+		},
+	}
+}
+
+// IsPublicIP reports whether the address is routable on the internet
+func IsPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, block := range activityPubBlockedNets {
+		if block.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateActivityPubURL checks the url before requesting it
+// Only https is allowed and the requests to this instance itself are rejected
+func ValidateActivityPubURL(uri *url.URL) error {
+	if uri.Scheme != "https" {
+		return fmt.Errorf("only https is allowed, got %s", uri.Scheme)
+	}
+	host := strings.ToLower(uri.Hostname())
+	if len(host) == 0 {
+		return fmt.Errorf("missing host in url")
+	}
+	if ip := net.ParseIP(host); ip != nil && !IsPublicIP(ip) {
+		return fmt.Errorf("%s is not a public address", host)
+	}
+	for _, key := range []string{"activitypub_base_url", "web_base_url"} {
+		if local, err := url.Parse(viper.GetString(key)); err == nil && strings.ToLower(local.Hostname()) == host {
+			return fmt.Errorf("requesting this instance itself is not allowed")
+		}
+	}
+	return nil
+}
+
+// readActivityPubBody reads the response body and fails when it is larger than the limit
+func readActivityPubBody(body io.Reader) ([]byte, error) {
+	raw, err := io.ReadAll(io.LimitReader(body, activityPubMaxResponseSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) > activityPubMaxResponseSize {
+		return nil, fmt.Errorf("response is larger than %d bytes", activityPubMaxResponseSize)
+	}
+	return raw, nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"github.com/go-ap/activitypub"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+// The local followers are the personal publishers of the accounts which subscribed the publisher
+func activityPubLocalFollowers(publisher models.Publisher) *gorm.DB {
+	return database.C.Model(&models.Publisher{}).
+		Joins("JOIN subscriptions ON subscriptions.follower_id = publishers.account_id AND subscriptions.deleted_at IS NULL").
+		Where("subscriptions.account_id = ? AND publishers.type = ?", publisher.ID, models.PublisherTypePersonal)
+}
+
+func activityPubRemoteFollowers(publisher models.Publisher) *gorm.DB {
+	return database.C.Model(&models.FediverseFollower{}).Where("publisher_id = ?", publisher.ID)
+}
+
+func CountActivityPubFollowers(publisher models.Publisher) (int64, error) {
+	var local, remote int64
+	if err := activityPubLocalFollowers(publisher).Count(&local).Error; err != nil {
+		return 0, err
+	}
+	if err := activityPubRemoteFollowers(publisher).Count(&remote).Error; err != nil {
+		return 0, err
+	}
+	return local + remote, nil
+}
+
+// ListActivityPubFollowers returns the actor IRIs of the publisher's followers
+// The local followers come first and then the ones from other instances
+func ListActivityPubFollowers(publisher models.Publisher, take int, offset int) ([]activitypub.Item, error) {
+	var local int64
+	if err := activityPubLocalFollowers(publisher).Count(&local).Error; err != nil {
+		return nil, err
+	}
+
+	var items []activitypub.Item
+	if int64(offset) < local {
+		var names []string
+		if err := activityPubLocalFollowers(publisher).
+			Order("subscriptions.created_at ASC").
+			Limit(take).Offset(offset).
+			Pluck("publishers.name", &names).Error; err != nil {
+			return nil, err
+		}
+		items = append(items, lo.Map(names, func(item string, index int) activitypub.Item {
+			return GetActivityIRI("/users/" + item)
+		})...)
+	}
+
+	if len(items) < take {
+		var actors []string
+		if err := activityPubRemoteFollowers(publisher).
+			Order("created_at ASC").
+			Limit(take-len(items)).Offset(max(offset-int(local), 0)).
+			Pluck("actor_id", &actors).Error; err != nil {
+			return nil, err
+		}
+		items = append(items, lo.Map(actors, func(item string, index int) activitypub.Item {
+			return activitypub.IRI(item)
+		})...)
+	}
+
+	return items, nil
+}
+
+// Only the personal publishers are following others, which follows the owner account's subscriptions
+func activityPubFollowing(publisher models.Publisher) *gorm.DB {
+	tx := database.C.Model(&models.Publisher{}).
+		Joins("JOIN subscriptions ON subscriptions.account_id = publishers.id AND subscriptions.deleted_at IS NULL")
+	if publisher.Type != models.PublisherTypePersonal || publisher.AccountID == nil {
+		return tx.Where("1 = 0")
+	}
+	return tx.Where("subscriptions.follower_id = ?", *publisher.AccountID)
+}
+
+func CountActivityPubFollowing(publisher models.Publisher) (int64, error) {
+	var count int64
+	err := activityPubFollowing(publisher).Count(&count).Error
+	return count, err
+}
+
+// ListActivityPubFollowing returns the actor IRIs of the publishers followed by the publisher
+func ListActivityPubFollowing(publisher models.Publisher, take int, offset int) ([]activitypub.Item, error) {
+	var targets []models.Publisher
+	if err := activityPubFollowing(publisher).
+		Order("subscriptions.created_at ASC").
+		Limit(take).Offset(offset).
+		Select("publishers.name", "publishers.fediverse_id").
+		Find(&targets).Error; err != nil {
+		return nil, err
+	}
+
+	return lo.Map(targets, func(item models.Publisher, index int) activitypub.Item {
+		if item.FediverseID != nil {
+			return activitypub.IRI(*item.FediverseID)
+		}
+		return GetActivityIRI("/users/" + item.Name)
+	}), nil
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"github.com/go-ap/activitypub"
+	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
+)
+
+const (
+	activityPubDeliveryMaxAttempts = 10
+	activityPubDeliveryBatchSize   = 100
+	activityPubDeliveryConcurrency = 8
+)
+
+var activityPubDeliveryLock sync.Mutex
+
+// IsPostFederated tells is the post should be sent to the other instances
+// Only the published public posts out of any realm will leave this instance
+func IsPostFederated(item models.Post) bool {
+	if item.FediverseID != nil {
+		return false
+	}
+	if item.IsDraft || item.ExpiredAt != nil || item.RealmID != nil || item.Visibility != models.PostVisibilityAll {
+		return false
+	}
+	if item.PublishedAt != nil && item.PublishedAt.After(time.Now()) {
+		return false
+	}
+	return true
+}
+
+// EnqueueActivityPubDelivery queues the activity for every remote follower of the publisher
+// The followers on the same instance will share one delivery if they have the shared inbox
+func EnqueueActivityPubDelivery(publisher models.Publisher, activity activitypub.Item) error {
+	var followers []models.FediverseFollower
+	if err := database.C.Where("publisher_id = ?", publisher.ID).Find(&followers).Error; err != nil {
+		return err
+	}
+	if len(followers) == 0 {
+		return nil
+	}
+
+	raw, err := MarshalActivityPub(activity)
+	if err != nil {
+		return err
+	}
+
+	inboxes := lo.Uniq(lo.Map(followers, func(item models.FediverseFollower, index int) string {
+		return lo.Ternary(len(item.SharedInbox) > 0, item.SharedInbox, item.Inbox)
+	}))
+	deliveries := lo.Map(inboxes, func(item string, index int) models.FediverseDelivery {
+		return models.FediverseDelivery{
+			Inbox:         item,
+			Activity:      raw,
+			Status:        models.FediverseDeliveryPending,
+			NextAttemptAt: time.Now(),
+			PublisherID:   publisher.ID,
+		}
+	})
+
+	return database.C.CreateInBatches(&deliveries, activityPubDeliveryBatchSize).Error
+}
+
+// FederatePost tells the remote followers about the changes of the post
+// Pass the original post when the post was edited, and the deleted flag when it was deleted
+func FederatePost(item models.Post, og *models.Post, deleted ...bool) {
+	var publisher models.Publisher
+	if err := database.C.Where("id = ?", item.PublisherID).First(&publisher).Error; err != nil {
+		log.Error().Err(err).Uint("post", item.ID).Msg("An error occurred when federating post...")
+		return
+	} else if publisher.Type == models.PublisherTypeFediverse {
+		return
+	}
+	item.Publisher = publisher
+
+	wasFederated := og != nil && IsPostFederated(*og)
+	isFederated := IsPostFederated(item)
+
+	var activity activitypub.Item
+	switch {
+	case len(deleted) > 0 && deleted[0]:
+		if isFederated {
+			activity = BuildActivityPubDelete(item)
+		}
+	case wasFederated && isFederated:
+		// The announcement cannot be updated, nothing in it will be changed anyway
+		if !IsActivityPubRepost(item) {
+			activity = BuildActivityPubUpdate(item)
+		}
+	case wasFederated && !isFederated:
+		// The post became invisible for the public, ask the followers to forget it
+		activity = BuildActivityPubDelete(item)
+	case !wasFederated && isFederated:
+		activity = BuildActivityPubActivity(item)
+	}
+	if activity == nil {
+		return
+	}
+
+	if err := EnqueueActivityPubDelivery(publisher, activity); err != nil {
+		log.Error().Err(err).Uint("post", item.ID).Msg("An error occurred when federating post...")
+	}
+}
+
+// FlushActivityPubDeliveries sends the due deliveries
+// The failed deliveries will be retried with exponential backoff until it reached the max attempts
+func FlushActivityPubDeliveries() {
+	if !activityPubDeliveryLock.TryLock() {
+		return
+	}
+	defer activityPubDeliveryLock.Unlock()
+
+	var deliveries []models.FediverseDelivery
+	if err := database.C.
+		Where("status = ? AND next_attempt_at <= ?", models.FediverseDeliveryPending, time.Now()).
+		Order("next_attempt_at ASC").
+		Limit(activityPubDeliveryBatchSize).
+		Preload("Publisher").
+		Find(&deliveries).Error; err != nil {
+		log.Error().Err(err).Msg("An error occurred when fetching activity deliveries...")
+		return
+	}
+	if len(deliveries) == 0 {
+		return
+	}
+
+	log.Debug().Int("count", len(deliveries)).Msg("Delivering activities to fediverse...")
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, activityPubDeliveryConcurrency)
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(delivery models.FediverseDelivery) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			flushActivityPubDelivery(delivery)
+		}(delivery)
+	}
+	wg.Wait()
+
+	log.Debug().Int("count", len(deliveries)).Dur("elapsed", time.Since(start)).Msg("Activities were delivered.")
+}
+
+func flushActivityPubDelivery(delivery models.FediverseDelivery) {
+	err := deliverActivityPubRaw(delivery.Publisher, delivery.Inbox, delivery.Activity)
+	if err == nil {
+		database.C.Unscoped().Delete(&delivery)
+		return
+	}
+
+	delivery.Attempts++
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= activityPubDeliveryMaxAttempts {
+		delivery.Status = models.FediverseDeliveryDead
+		log.Warn().Err(err).Uint("delivery", delivery.ID).Str("inbox", delivery.Inbox).Msg("Activity delivery was given up...")
+	} else {
+		delivery.NextAttemptAt = time.Now().Add(time.Minute << (delivery.Attempts - 1))
+	}
+
+	database.C.Model(&delivery).Select("attempts", "last_error", "status", "next_attempt_at").Updates(&delivery)
+}
+
+// RetryActivityPubDelivery puts a dead delivery back into the queue
+func RetryActivityPubDelivery(delivery models.FediverseDelivery) (models.FediverseDelivery, error) {
+	delivery.Status = models.FediverseDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	err := database.C.Model(&delivery).Select("attempts", "status", "next_attempt_at").Updates(&delivery).Error
+	return delivery, err
+}
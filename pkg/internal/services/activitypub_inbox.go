@@ -0,0 +1,410 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"github.com/go-ap/activitypub"
+	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+// FediverseReactionSymbol is the symbol used to store the Like from other instances
+const FediverseReactionSymbol = "thumb_up"
+
+func HandleActivityPubInbox(publisher models.Publisher, activity *activitypub.Activity) error {
+	if activity.Actor == nil || activity.Object == nil {
+		return fmt.Errorf("activity has no actor or object")
+	}
+
+	switch activity.Type {
+	case activitypub.FollowType:
+		return HandleActivityPubFollow(publisher, activity)
+	case activitypub.UndoType:
+		return HandleActivityPubUndo(publisher, activity)
+	case activitypub.LikeType:
+		return HandleActivityPubLike(activity)
+	case activitypub.AnnounceType:
+		return HandleActivityPubAnnounce(activity)
+	case activitypub.CreateType:
+		return HandleActivityPubCreate(activity)
+	default:
+		log.Debug().Str("type", string(activity.Type)).Str("publisher", publisher.Name).Msg("Unhandled activity type received...")
+	}
+
+	return nil
+}
+
+func HandleActivityPubFollow(publisher models.Publisher, activity *activitypub.Activity) error {
+	if publisher.ID == 0 {
+		return fmt.Errorf("unable to find publisher to follow")
+	}
+
+	actor, err := FetchActivityPubActor(activity.Actor.GetLink().String())
+	if err != nil {
+		return err
+	}
+
+	follower := models.FediverseFollower{
+		ActorID:     actor.ID.String(),
+		Inbox:       getActivityPubItemUrl(actor.Inbox),
+		FollowID:    activity.ID.String(),
+		PublisherID: publisher.ID,
+	}
+	if actor.Endpoints != nil {
+		follower.SharedInbox = getActivityPubItemUrl(actor.Endpoints.SharedInbox)
+	}
+	if len(follower.Inbox) == 0 {
+		return fmt.Errorf("actor %s has no inbox", follower.ActorID)
+	}
+
+	if err := database.C.
+		Where("actor_id = ? AND publisher_id = ?", follower.ActorID, publisher.ID).
+		Assign(models.FediverseFollower{Inbox: follower.Inbox, SharedInbox: follower.SharedInbox, FollowID: follower.FollowID}).
+		FirstOrCreate(&follower).Error; err != nil {
+		return err
+	}
+
+	accept := activitypub.AcceptNew(GetActivityID(fmt.Sprintf("/activities/accepts/%d", follower.ID)), activity)
+	accept.Actor = GetActivityIRI("/users/" + publisher.Name)
+	accept.To = activitypub.ItemCollection{activity.Actor.GetLink()}
+	go func() {
+		if err := DeliverActivityPubActivity(publisher, follower.Inbox, accept); err != nil {
+			log.Error().Err(err).Str("actor", follower.ActorID).Msg("An error occurred when accepting follow request...")
+		}
+	}()
+
+	return nil
+}
+
+func HandleActivityPubUndo(publisher models.Publisher, activity *activitypub.Activity) error {
+	actorID := activity.Actor.GetLink().String()
+	objectID := activity.Object.GetLink().String()
+	objectType := activity.Object.GetType()
+
+	if objectType == activitypub.FollowType || objectType == "" {
+		// The Undo only has the link of the Follow, find the follower by the activity id instead
+		tx := database.C.Unscoped().Where("actor_id = ?", actorID)
+		if publisher.ID > 0 {
+			tx = tx.Where("publisher_id = ?", publisher.ID)
+		} else {
+			tx = tx.Where("follow_id = ?", objectID)
+		}
+		tx = tx.Delete(&models.FediverseFollower{})
+		if tx.Error != nil {
+			return tx.Error
+		} else if tx.RowsAffected > 0 {
+			return nil
+		}
+	}
+	if objectType == activitypub.LikeType || objectType == "" {
+		var reaction models.Reaction
+		if err := database.C.Where("fediverse_id = ?", objectID).First(&reaction).Error; err == nil {
+			// The reactions stored before the actor was recorded are checked by the origin of the Like
+			isOwner := lo.TernaryF(
+				reaction.FediverseActor != nil,
+				func() bool { return *reaction.FediverseActor == actorID },
+				func() bool { return IsSameActivityPubOrigin(objectID, actorID) },
+			)
+			if !isOwner {
+				return fmt.Errorf("actor %s cannot undo the like of others", actorID)
+			}
+			return removeFediverseReaction(reaction)
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+	if objectType == activitypub.AnnounceType || objectType == "" {
+		var repost models.Post
+		if err := database.C.
+			Where("fediverse_id = ? AND publisher_id IN (?)", objectID, database.C.
+				Model(&models.Publisher{}).
+				Select("id").
+				Where("fediverse_id = ?", actorID)).
+			First(&repost).Error; err == nil {
+			return database.C.Delete(&repost).Error
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func HandleActivityPubLike(activity *activitypub.Activity) error {
+	postID, ok := ParseActivityPostID(activity.Object.GetLink().String())
+	if !ok {
+		return nil
+	}
+
+	likeID := activity.ID.String()
+	if len(likeID) == 0 {
+		return fmt.Errorf("like activity has no id")
+	}
+	actorID := activity.Actor.GetLink().String()
+	if !IsSameActivityPubOrigin(likeID, actorID) {
+		return fmt.Errorf("like activity and its actor are not on the same origin")
+	}
+	var reaction models.Reaction
+	if err := database.C.Where("fediverse_id = ?", likeID).First(&reaction).Error; err == nil {
+		return nil
+	}
+
+	var op models.Post
+	if err := database.C.
+		Where("id = ? AND visibility = ?", postID, models.PostVisibilityAll).
+		Preload("Publisher").
+		First(&op).Error; err != nil {
+		return fmt.Errorf("unable to find post to react: %v", err)
+	}
+	if err := EnsurePostReactable(op); err != nil {
+		return err
+	}
+
+	actor, err := FetchActivityPubActor(actorID)
+	if err != nil {
+		return err
+	}
+	user, err := GetFediversePublisher(actor)
+	if err != nil {
+		return err
+	}
+
+	reaction = models.Reaction{
+		Symbol:         FediverseReactionSymbol,
+		Attitude:       models.AttitudePositive,
+		PostID:         op.ID,
+		FediverseID:    &likeID,
+		FediverseActor: &actorID,
+	}
+	if err := database.C.Save(&reaction).Error; err != nil {
+		return err
+	}
+
+	_ = ModifyPosterVoteCount(op.Publisher, true, 1)
+	database.C.Model(&op).Update("total_upvote", gorm.Expr("total_upvote + 1"))
+
+	if op.Publisher.AccountID != nil {
+		err = NotifyPosterAccount(
+			op.Publisher,
+			op,
+			"Post got reacted",
+			fmt.Sprintf("%s (%s) reacted your post a %s.", user.Nick, user.Name, reaction.Symbol),
+			"interactive.feedback",
+			fmt.Sprintf("%s reacted you", user.Nick),
+		)
+		if err != nil {
+			log.Error().Err(err).Msg("An error occurred when notifying user...")
+		}
+	}
+
+	return nil
+}
+
+// BuildActivityPubPost converts the remote note into a post of the remote publisher
+// The post is public by default, the caller should decide the visibility by the addressing
+func BuildActivityPubPost(note *activitypub.Object, publisher models.Publisher) models.Post {
+	noteID := note.ID.String()
+	content := ConvertActivityPubContent(note.Content.String())
+	body := map[string]any{"content": content}
+	if len(note.Name) > 0 {
+		body["title"] = note.Name.String()
+	}
+	if url := getActivityPubItemUrl(note.URL); len(url) > 0 {
+		body["fediverse_url"] = url
+	}
+	if note.Attachment != nil {
+		items := activitypub.ItemCollection{note.Attachment}
+		if note.Attachment.IsCollection() {
+			_ = activitypub.OnCollectionIntf(note.Attachment, func(col activitypub.CollectionInterface) error {
+				items = col.Collection()
+				return nil
+			})
+		}
+		body["fediverse_attachments"] = lo.Map(items, func(item activitypub.Item, index int) map[string]any {
+			return map[string]any{
+				"type": strings.ToLower(string(item.GetType())),
+				"url":  getActivityPubItemUrl(item),
+			}
+		})
+	}
+
+	return models.Post{
+		Type:        models.PostTypeStory,
+		Body:        body,
+		Language:    DetectLanguage(content),
+		Visibility:  models.PostVisibilityAll,
+		PublishedAt: lo.ToPtr(lo.Ternary(note.Published.IsZero(), time.Now(), note.Published)),
+		PublisherID: publisher.ID,
+		FediverseID: &noteID,
+	}
+}
+
+func IsActivityPubPublic(object *activitypub.Object) bool {
+	return object.To.Contains(activitypub.PublicNS) || object.CC.Contains(activitypub.PublicNS)
+}
+
+func removeFediverseReaction(reaction models.Reaction) error {
+	var op models.Post
+	if err := database.C.
+		Where("id = ?", reaction.PostID).
+		Preload("Publisher").
+		First(&op).Error; err != nil {
+		return err
+	}
+
+	if err := database.C.Delete(&reaction).Error; err != nil {
+		return err
+	}
+
+	_ = ModifyPosterVoteCount(op.Publisher, true, -1)
+	return database.C.Model(&op).Update("total_upvote", gorm.Expr("total_upvote - 1")).Error
+}
+
+func HandleActivityPubAnnounce(activity *activitypub.Activity) error {
+	postID, ok := ParseActivityPostID(activity.Object.GetLink().String())
+	if !ok {
+		return nil
+	}
+
+	announceID := activity.ID.String()
+	if len(announceID) == 0 {
+		return fmt.Errorf("announce activity has no id")
+	}
+	actorID := activity.Actor.GetLink().String()
+	if !IsSameActivityPubOrigin(announceID, actorID) {
+		return fmt.Errorf("announce activity and its actor are not on the same origin")
+	}
+	var repost models.Post
+	if err := database.C.Where("fediverse_id = ?", announceID).First(&repost).Error; err == nil {
+		return nil
+	}
+
+	var op models.Post
+	if err := database.C.Where("id = ? AND visibility = ?", postID, models.PostVisibilityAll).First(&op).Error; err != nil {
+		return fmt.Errorf("unable to find post to repost: %v", err)
+	}
+
+	actor, err := FetchActivityPubActor(actorID)
+	if err != nil {
+		return err
+	}
+	publisher, err := GetFediversePublisher(actor)
+	if err != nil {
+		return err
+	}
+
+	repost = models.Post{
+		Type:        models.PostTypeStory,
+		Body:        map[string]any{"content": ""},
+		Language:    op.Language,
+		RepostID:    &op.ID,
+		Visibility:  models.PostVisibilityAll,
+		PublishedAt: lo.ToPtr(lo.Ternary(activity.Published.IsZero(), time.Now(), activity.Published)),
+		PublisherID: publisher.ID,
+		FediverseID: &announceID,
+	}
+
+	return database.C.Save(&repost).Error
+}
+
+func HandleActivityPubCreate(activity *activitypub.Activity) error {
+	actorID := activity.Actor.GetLink().String()
+	object := activity.Object
+	if object.IsLink() {
+		if !IsSameActivityPubOrigin(object.GetLink().String(), actorID) {
+			return fmt.Errorf("created object and the actor are not on the same origin")
+		}
+		var err error
+		if object, err = FetchActivityPubObject(object.GetLink().String()); err != nil {
+			return err
+		}
+	}
+
+	note, err := activitypub.ToObject(object)
+	if err != nil {
+		return fmt.Errorf("unable to parse created object: %v", err)
+	}
+	if note.Type != activitypub.NoteType || note.InReplyTo == nil {
+		return nil
+	}
+
+	replyID, ok := ParseActivityPostID(note.InReplyTo.GetLink().String())
+	if !ok {
+		return nil
+	}
+
+	// Only the author can create the note, and on the origin it came from
+	noteID := note.ID.String()
+	if len(noteID) == 0 || !IsSameActivityPubOrigin(noteID, actorID) {
+		return fmt.Errorf("created object and the actor are not on the same origin")
+	}
+	if note.AttributedTo == nil || note.AttributedTo.GetLink().String() != actorID {
+		return fmt.Errorf("created object is not attributed to the actor")
+	}
+
+	var item models.Post
+	if err := database.C.Where("fediverse_id = ?", noteID).First(&item).Error; err == nil {
+		return nil
+	}
+
+	var op models.Post
+	if err := database.C.Where("id = ?", replyID).Preload("Publisher").First(&op).Error; err != nil {
+		return fmt.Errorf("unable to find post to reply: %v", err)
+	}
+	// The posts not federated are unknown to the other instances, they cannot be replied from there
+	if !IsPostFederated(op) {
+		return fmt.Errorf("unable to find post to reply: post is not federated")
+	}
+	if err := EnsurePostRepliable(op.ID); err != nil {
+		return err
+	}
+
+	actor, err := FetchActivityPubActor(actorID)
+	if err != nil {
+		return err
+	}
+	publisher, err := GetFediversePublisher(actor)
+	if err != nil {
+		return err
+	}
+
+	item = BuildActivityPubPost(note, publisher)
+	item.ReplyID = &op.ID
+
+	// Only the public replies are visible for everyone
+	// Others are only visible for the original poster
+	if !IsActivityPubPublic(note) {
+		item.Visibility = models.PostVisibilitySelected
+		if op.Publisher.AccountID != nil {
+			item.VisibleUsers = []uint{*op.Publisher.AccountID}
+		}
+	}
+
+	if err := database.C.Save(&item).Error; err != nil {
+		return err
+	}
+
+	go NotifyReplying(item, publisher)
+
+	return nil
+}
+
+// IsSameActivityPubOrigin reports whether the two IRIs are on the same host
+func IsSameActivityPubOrigin(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil || len(ua.Host) == 0 {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil || len(ub.Host) == 0 {
+		return false
+	}
+	return strings.EqualFold(ua.Host, ub.Host)
+}
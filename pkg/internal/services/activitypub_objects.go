@@ -0,0 +1,308 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/gap"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"git.solsynth.dev/hypernet/paperclip/pkg/filekit"
+	fmodels "git.solsynth.dev/hypernet/paperclip/pkg/filekit/models"
+	"github.com/go-ap/activitypub"
+	"github.com/goccy/go-json"
+	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
+)
+
+// ActivityPubHashtagType is the tag type used by Mastodon, it is not a part of the standard vocabulary
+const ActivityPubHashtagType = activitypub.ActivityVocabularyType("Hashtag")
+
+// BuildActivityPubActor returns the actor profile of a local publisher
+// The keypair of the publisher is required to be generated before
+func BuildActivityPubActor(publisher models.Publisher) activitypub.Actor {
+	id := GetActivityID("/users/" + publisher.Name)
+	actor := activitypub.Actor{
+		ID:                id,
+		Inbox:             id + "/inbox",
+		Outbox:            id + "/outbox",
+		Followers:         id + "/followers",
+		Following:         id + "/following",
+		Type:              activitypub.PersonType,
+		Name:              activitypub.DefaultNaturalLanguageValue(publisher.Nick),
+		PreferredUsername: activitypub.DefaultNaturalLanguageValue(publisher.Name),
+		Summary:           activitypub.DefaultNaturalLanguageValue(renderActivityPubContent(publisher.Description)),
+		URL:               activitypub.IRI(GetPublisherPageUrl(publisher)),
+		Published:         publisher.CreatedAt,
+		Endpoints: &activitypub.Endpoints{
+			SharedInbox: GetActivityIRI("/inbox"),
+		},
+		PublicKey: activitypub.PublicKey{
+			ID:           activitypub.ID(GetActivityPubKeyID(publisher)),
+			Owner:        activitypub.IRI(id),
+			PublicKeyPem: publisher.PublicKey,
+		},
+	}
+
+	switch publisher.Type {
+	case models.PublisherTypeOrganization:
+		actor.Type = activitypub.OrganizationType
+	case models.PublisherTypeAnonymous:
+		actor.Type = activitypub.ServiceType
+	}
+
+	if len(publisher.Avatar) > 0 {
+		actor.Icon = &activitypub.Object{
+			Type: activitypub.ImageType,
+			URL:  activitypub.IRI(GetAttachmentUrl(publisher.Avatar)),
+		}
+	}
+	if len(publisher.Banner) > 0 {
+		actor.Image = &activitypub.Object{
+			Type: activitypub.ImageType,
+			URL:  activitypub.IRI(GetAttachmentUrl(publisher.Banner)),
+		}
+	}
+
+	return actor
+}
+
+// activityPubPostBody contains all the fields used to render the post among all the post types
+type activityPubPostBody struct {
+	Title       *string  `json:"title"`
+	Description *string  `json:"description"`
+	Content     string   `json:"content"`
+	Video       *string  `json:"video"`
+	Attachments []string `json:"attachments"`
+}
+
+func GetActivityPubPostID(item models.Post) activitypub.ID {
+	return GetActivityID(fmt.Sprintf("/posts/%d", item.ID))
+}
+
+// GetActivityPubPostIRI returns the object IRI of a post by its id
+// The posts from other instances will use their original IRI
+func GetActivityPubPostIRI(id uint) activitypub.IRI {
+	var item models.Post
+	if err := database.C.Select("id", "fediverse_id").Where("id = ?", id).First(&item).Error; err == nil && item.FediverseID != nil {
+		return activitypub.IRI(*item.FediverseID)
+	}
+	return GetActivityIRI(fmt.Sprintf("/posts/%d", id))
+}
+
+// GetActivityPubAudience maps the post visibility into the addressing fields
+// The posts visible to the selected users only will not be addressed to anyone outside
+func GetActivityPubAudience(item models.Post) (to activitypub.ItemCollection, cc activitypub.ItemCollection) {
+	followers := GetActivityIRI("/users/" + item.Publisher.Name + "/followers")
+	switch item.Visibility {
+	case models.PostVisibilityAll:
+		return activitypub.ItemCollection{activitypub.PublicNS}, activitypub.ItemCollection{followers}
+	case models.PostVisibilityFriends, models.PostVisibilityFiltered:
+		return activitypub.ItemCollection{followers}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// IsActivityPubRepost tells is the post a repost without any own content, which should be an Announce
+func IsActivityPubRepost(item models.Post) bool {
+	if item.RepostID == nil {
+		return false
+	}
+	var body activityPubPostBody
+	raw, _ := json.Marshal(item.Body)
+	_ = json.Unmarshal(raw, &body)
+	return len(strings.TrimSpace(body.Content)) == 0 && len(body.Attachments) == 0
+}
+
+// BuildActivityPubNote converts the post into the object which other instances can understand
+// The publisher of the post is required to be preloaded, so do the tags and categories
+func BuildActivityPubNote(item models.Post) *activitypub.Object {
+	var body activityPubPostBody
+	{
+		raw, _ := json.Marshal(item.Body)
+		_ = json.Unmarshal(raw, &body)
+	}
+
+	to, cc := GetActivityPubAudience(item)
+	note := &activitypub.Object{
+		ID:           GetActivityPubPostID(item),
+		Type:         activitypub.NoteType,
+		AttributedTo: GetActivityIRI("/users/" + item.Publisher.Name),
+		Published: lo.TernaryF(item.PublishedAt == nil, func() time.Time {
+			return item.CreatedAt
+		}, func() time.Time {
+			return *item.PublishedAt
+		}),
+		Updated: lo.TernaryF(item.EditedAt == nil, func() time.Time {
+			return item.UpdatedAt
+		}, func() time.Time {
+			return *item.EditedAt
+		}),
+		To:        to,
+		CC:        cc,
+		MediaType: "text/html",
+		Source: activitypub.Source{
+			Content:   activitypub.DefaultNaturalLanguageValue(body.Content),
+			MediaType: "text/markdown",
+		},
+	}
+
+	content := body.Content
+	if len(content) == 0 && body.Description != nil {
+		content = *body.Description
+	}
+
+	switch item.Type {
+	case models.PostTypeArticle:
+		note.Type = activitypub.ArticleType
+		if body.Title != nil {
+			note.Name = activitypub.DefaultNaturalLanguageValue(*body.Title)
+		}
+		if body.Description != nil {
+			note.Summary = activitypub.DefaultNaturalLanguageValue(*body.Description)
+		}
+		note.Content = activitypub.DefaultNaturalLanguageValue(renderActivityPubContent(content))
+	default:
+		// Note has no title, so we put it at the beginning of the content
+		rendered := renderActivityPubContent(content)
+		if body.Title != nil && len(*body.Title) > 0 {
+			rendered = "<p><strong>" + html.EscapeString(*body.Title) + "</strong></p>" + rendered
+		}
+		note.Content = activitypub.DefaultNaturalLanguageValue(rendered)
+	}
+
+	if item.ReplyID != nil {
+		note.InReplyTo = GetActivityPubPostIRI(*item.ReplyID)
+	}
+
+	for _, tag := range item.Tags {
+		note.Tag = append(note.Tag, &activitypub.Link{
+			Type: ActivityPubHashtagType,
+			Name: activitypub.DefaultNaturalLanguageValue("#" + tag.Alias),
+		})
+	}
+	for _, category := range item.Categories {
+		note.Tag = append(note.Tag, &activitypub.Link{
+			Type: ActivityPubHashtagType,
+			Name: activitypub.DefaultNaturalLanguageValue("#" + category.Alias),
+		})
+	}
+
+	rids := body.Attachments
+	if body.Video != nil && !strings.HasPrefix(*body.Video, "http") {
+		rids = append(rids, *body.Video)
+	}
+	if len(rids) > 0 {
+		attachments, err := filekit.ListAttachment(gap.Nx, lo.Uniq(rids))
+		if err != nil {
+			log.Error().Err(err).Uint("post", item.ID).Msg("An error occurred when loading attachments for federating...")
+		}
+		if len(attachments) > 0 {
+			note.Attachment = activitypub.ItemCollection(lo.Map(attachments, func(item fmodels.Attachment, index int) activitypub.Item {
+				return buildActivityPubAttachment(item)
+			}))
+		}
+		// Use content warning to mark the post as sensitive, which most of the implementations will respect
+		if len(note.Summary) == 0 && lo.SomeBy(attachments, func(item fmodels.Attachment) bool {
+			return item.IsMature
+		}) {
+			note.Summary = activitypub.DefaultNaturalLanguageValue("Sensitive content")
+		}
+	}
+
+	return note
+}
+
+func buildActivityPubAttachment(attachment fmodels.Attachment) *activitypub.Object {
+	object := &activitypub.Object{
+		Type:      activitypub.DocumentType,
+		MediaType: activitypub.MimeType(attachment.MimeType),
+		URL:       activitypub.IRI(GetAttachmentUrl(attachment.Rid)),
+		Name:      activitypub.DefaultNaturalLanguageValue(lo.Ternary(len(attachment.Alt) > 0, attachment.Alt, attachment.Name)),
+	}
+	switch strings.SplitN(attachment.MimeType, "/", 2)[0] {
+	case "image":
+		object.Type = activitypub.ImageType
+	case "video":
+		object.Type = activitypub.VideoType
+	case "audio":
+		object.Type = activitypub.AudioType
+	}
+	return object
+}
+
+// renderActivityPubContent turns the plain text content into the simple HTML
+// Most of the implementations only accept the HTML content
+func renderActivityPubContent(content string) string {
+	var paragraphs []string
+	for _, paragraph := range strings.Split(strings.TrimSpace(content), "\n\n") {
+		if paragraph = strings.TrimSpace(paragraph); len(paragraph) == 0 {
+			continue
+		}
+		lines := strings.Split(html.EscapeString(paragraph), "\n")
+		paragraphs = append(paragraphs, "<p>"+strings.Join(lines, "<br>")+"</p>")
+	}
+	return strings.Join(paragraphs, "")
+}
+
+// BuildActivityPubActivity returns the activity that represents the post was published
+// It is an Announce for the reposts and a Create for the others
+func BuildActivityPubActivity(item models.Post) *activitypub.Activity {
+	if IsActivityPubRepost(item) {
+		return BuildActivityPubAnnounce(item)
+	}
+	return BuildActivityPubCreate(item)
+}
+
+func BuildActivityPubCreate(item models.Post) *activitypub.Activity {
+	note := BuildActivityPubNote(item)
+	activity := activitypub.CreateNew(GetActivityID(fmt.Sprintf("/activities/posts/%d", item.ID)), note)
+	activity.Actor = note.AttributedTo
+	activity.Published = note.Published
+	activity.To = note.To
+	activity.CC = note.CC
+	return activity
+}
+
+func BuildActivityPubAnnounce(item models.Post) *activitypub.Activity {
+	activity := activitypub.AnnounceNew(GetActivityID(fmt.Sprintf("/activities/posts/%d", item.ID)), GetActivityPubPostIRI(*item.RepostID))
+	activity.Actor = GetActivityIRI("/users/" + item.Publisher.Name)
+	activity.Published = lo.FromPtrOr(item.PublishedAt, item.CreatedAt)
+	activity.To, activity.CC = GetActivityPubAudience(item)
+	return activity
+}
+
+func BuildActivityPubUpdate(item models.Post) *activitypub.Activity {
+	note := BuildActivityPubNote(item)
+	activity := activitypub.UpdateNew(GetActivityID(fmt.Sprintf("/activities/posts/%d/updates/%d", item.ID, note.Updated.Unix())), note)
+	activity.Actor = note.AttributedTo
+	activity.Published = note.Updated
+	activity.To = note.To
+	activity.CC = note.CC
+	return activity
+}
+
+// BuildActivityPubDelete returns the activity to withdraw the post
+// The reposts will be undone instead of deleted
+func BuildActivityPubDelete(item models.Post) *activitypub.Activity {
+	actor := GetActivityIRI("/users/" + item.Publisher.Name)
+	if IsActivityPubRepost(item) {
+		announce := BuildActivityPubAnnounce(item)
+		activity := activitypub.UndoNew(GetActivityID(fmt.Sprintf("/activities/posts/%d/undo", item.ID)), announce)
+		activity.Actor = actor
+		activity.To, activity.CC = announce.To, announce.CC
+		return activity
+	}
+
+	tombstone := &activitypub.Object{
+		ID:   GetActivityPubPostID(item),
+		Type: activitypub.TombstoneType,
+	}
+	activity := activitypub.DeleteNew(GetActivityID(fmt.Sprintf("/activities/posts/%d/delete", item.ID)), tombstone)
+	activity.Actor = actor
+	activity.To = activitypub.ItemCollection{activitypub.PublicNS}
+	return activity
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/gap"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"git.solsynth.dev/hypernet/nexus/pkg/nex/cachekit"
+	"github.com/go-ap/activitypub"
+	"github.com/goccy/go-json"
+	"github.com/samber/lo"
+	"github.com/spf13/viper"
+)
+
+const activityPubSignatureWindow = 12 * time.Hour
+
+func GetActivityPubKeyID(publisher models.Publisher) string {
+	return GetActivityID("/users/"+publisher.Name).String() + "#main-key"
+}
+
+// EnsurePublisherKeypair will generate the keypair for publisher if it does not have one yet
+// The keypair is only saved when the publisher still has none, so the concurrent callers end up with the same one
+func EnsurePublisherKeypair(publisher models.Publisher) (models.Publisher, error) {
+	if len(publisher.PrivateKey) > 0 && len(publisher.PublicKey) > 0 {
+		return publisher, nil
+	}
+	if publisher.Type == models.PublisherTypeFediverse {
+		return publisher, fmt.Errorf("remote publisher cannot have a keypair")
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return publisher, fmt.Errorf("failed to generate keypair: %v", err)
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return publisher, fmt.Errorf("failed to encode public key: %v", err)
+	}
+
+	if err := database.C.Model(&models.Publisher{}).
+		Where("id = ? AND (private_key IS NULL OR private_key = '')", publisher.ID).
+		Updates(map[string]any{
+			"private_key": string(pem.EncodeToMemory(&pem.Block{
+				Type:  "RSA PRIVATE KEY",
+				Bytes: x509.MarshalPKCS1PrivateKey(key),
+			})),
+			"public_key": string(pem.EncodeToMemory(&pem.Block{
+				Type:  "PUBLIC KEY",
+				Bytes: publicKey,
+			})),
+		}).Error; err != nil {
+		return publisher, err
+	}
+
+	// Read it back, the keypair may be the one generated by the other caller
+	if err := database.C.Where("id = ?", publisher.ID).First(&publisher).Error; err != nil {
+		return publisher, err
+	}
+	return publisher, nil
+}
+
+// SignActivityPubRequest signs the request with the publisher's private key
+// Following the draft-cavage-http-signatures which is used by Mastodon and Misskey
+func SignActivityPubRequest(publisher models.Publisher, req *http.Request, body []byte) error {
+	publisher, err := EnsurePublisherKeypair(publisher)
+	if err != nil {
+		return err
+	}
+	block, _ := pem.Decode([]byte(publisher.PrivateKey))
+	if block == nil {
+		return fmt.Errorf("invalid private key of publisher %s", publisher.Name)
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("invalid private key of publisher %s: %v", publisher.Name, err)
+	}
+
+	headers := []string{"(request-target)", "host", "date"}
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	if body != nil {
+		digest := sha256.Sum256(body)
+		req.Header.Set("Digest", "SHA-256="+base64.StdEncoding.EncodeToString(digest[:]))
+		headers = append(headers, "digest")
+	}
+
+	signingString := buildActivityPubSigningString(headers, req.Method, req.URL.RequestURI(), func(name string) string {
+		if name == "host" {
+			return req.URL.Host
+		}
+		return req.Header.Get(name)
+	})
+	hashed := sha256.Sum256([]byte(signingString))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return fmt.Errorf("failed to sign request: %v", err)
+	}
+
+	req.Header.Set("Signature", fmt.Sprintf(
+		`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		GetActivityPubKeyID(publisher),
+		strings.Join(headers, " "),
+		base64.StdEncoding.EncodeToString(signature),
+	))
+
+	return nil
+}
+
+// VerifyActivityPubRequest checks the signature of an incoming request and returns the actor who signed it
+// The uri is the path related to the activitypub base url, the host and path of the base url will be used
+// to rebuild the request target because we are usually behind the gateway
+func VerifyActivityPubRequest(method, uri string, header func(string) string, body []byte) (*activitypub.Actor, error) {
+	raw := header("Signature")
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("missing signature")
+	}
+	params := parseActivityPubSignature(raw)
+	keyID, signature := params["keyId"], params["signature"]
+	if len(keyID) == 0 || len(signature) == 0 {
+		return nil, fmt.Errorf("invalid signature")
+	}
+	if algorithm, ok := params["algorithm"]; ok && algorithm != "rsa-sha256" && algorithm != "hs2019" {
+		return nil, fmt.Errorf("unsupported signature algorithm %s", algorithm)
+	}
+	// The request must be bound to its target and time, otherwise it can be replayed to another inbox or later
+	headers := strings.Fields(strings.ToLower(params["headers"]))
+	required := []string{"(request-target)", "host", "date"}
+	if len(body) > 0 {
+		required = append(required, "digest")
+	}
+	if missing, _ := lo.Difference(required, headers); len(missing) > 0 {
+		return nil, fmt.Errorf("signature does not cover %s", strings.Join(missing, ", "))
+	}
+
+	if date, err := http.ParseTime(header("Date")); err != nil {
+		return nil, fmt.Errorf("invalid date header: %v", err)
+	} else if time.Since(date).Abs() > activityPubSignatureWindow {
+		return nil, fmt.Errorf("signature was expired")
+	}
+	if len(body) > 0 {
+		digest := sha256.Sum256(body)
+		if header("Digest") != "SHA-256="+base64.StdEncoding.EncodeToString(digest[:]) {
+			return nil, fmt.Errorf("digest mismatch")
+		}
+	}
+
+	baseUrl, err := url.Parse(viper.GetString("activitypub_base_url"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid activitypub base url: %v", err)
+	}
+	signingString := buildActivityPubSigningString(headers, method, baseUrl.Path+uri, func(name string) string {
+		if name == "host" {
+			return baseUrl.Host
+		}
+		return header(name)
+	})
+	hashed := sha256.Sum256([]byte(signingString))
+	decoded, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature encoding: %v", err)
+	}
+
+	verify := func(noCache bool) (*activitypub.Actor, error) {
+		actorID, err := resolveActivityPubKeyOwner(keyID, noCache)
+		if err != nil {
+			return nil, err
+		}
+		actor, err := FetchActivityPubActor(actorID, noCache)
+		if err != nil {
+			return nil, err
+		}
+		if actor.PublicKey.ID.String() != keyID {
+			return actor, fmt.Errorf("key %s does not belong to actor %s", keyID, actor.ID)
+		}
+		block, _ := pem.Decode([]byte(actor.PublicKey.PublicKeyPem))
+		if block == nil {
+			return actor, fmt.Errorf("invalid public key of actor %s", actor.ID)
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return actor, fmt.Errorf("invalid public key of actor %s: %v", actor.ID, err)
+		}
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return actor, fmt.Errorf("unsupported public key type of actor %s", actor.ID)
+		}
+		return actor, rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, hashed[:], decoded)
+	}
+
+	actor, err := verify(false)
+	if err != nil {
+		// The actor may rotate their key, try again without the cache
+		if actor, err = verify(true); err != nil {
+			return nil, fmt.Errorf("failed to verify signature: %v", err)
+		}
+	}
+
+	return actor, nil
+}
+
+// resolveActivityPubKeyOwner finds the actor of the key
+// The key id is usually a fragment of the actor, otherwise the key document is fetched to read its owner
+func resolveActivityPubKeyOwner(keyID string, noCache bool) (string, error) {
+	if strings.Contains(keyID, "#") {
+		return strings.SplitN(keyID, "#", 2)[0], nil
+	}
+
+	cacheKey := fmt.Sprintf("activitypub-key-owner#%s", keyID)
+	if !noCache {
+		if cached, err := cachekit.Get[string](gap.Ca, cacheKey); err == nil && len(cached) > 0 {
+			return cached, nil
+		}
+	}
+
+	raw, err := fetchActivityPubRaw(keyID)
+	if err != nil {
+		return "", err
+	}
+	var document struct {
+		ID        string          `json:"id"`
+		Owner     string          `json:"owner"`
+		PublicKey json.RawMessage `json:"publicKey"`
+	}
+	if err := json.Unmarshal(raw, &document); err != nil {
+		return "", fmt.Errorf("invalid key document %s: %v", keyID, err)
+	}
+
+	owner := document.Owner
+	if len(owner) == 0 && len(document.PublicKey) > 0 {
+		// Some implementations serve the actor itself at the key id
+		owner = document.ID
+	}
+	if len(owner) == 0 {
+		return "", fmt.Errorf("key %s has no owner", keyID)
+	}
+	if !IsSameActivityPubOrigin(keyID, owner) {
+		return "", fmt.Errorf("key %s and its owner are not on the same origin", keyID)
+	}
+
+	cachekit.Set[string](gap.Ca, cacheKey, owner, 30*time.Minute)
+	return owner, nil
+}
+
+func buildActivityPubSigningString(headers []string, method, target string, value func(string) string) string {
+	lines := make([]string, 0, len(headers))
+	for _, name := range headers {
+		if name == "(request-target)" {
+			lines = append(lines, fmt.Sprintf("(request-target): %s %s", strings.ToLower(method), target))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s: %s", name, value(name)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func parseActivityPubSignature(raw string) map[string]string {
+	params := make(map[string]string)
+	for _, part := range strings.Split(raw, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		params[kv[0]] = strings.Trim(kv[1], `"`)
+	}
+	return params
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+type InstanceStats struct {
+	Posts               int64 `json:"posts"`
+	PostsToday          int64 `json:"posts_today"`
+	FediversePosts      int64 `json:"fediverse_posts"`
+	Publishers          int64 `json:"publishers"`
+	FediversePublishers int64 `json:"fediverse_publishers"`
+	SuspendedPublishers int64 `json:"suspended_publishers"`
+	Reactions           int64 `json:"reactions"`
+	Tags                int64 `json:"tags"`
+	Categories          int64 `json:"categories"`
+	PendingReports      int64 `json:"pending_reports"`
+	PendingDeliveries   int64 `json:"pending_deliveries"`
+	DeadDeliveries      int64 `json:"dead_deliveries"`
+}
+
+func GetInstanceStats() (InstanceStats, error) {
+	var stats InstanceStats
+	counters := []struct {
+		tx  *gorm.DB
+		out *int64
+	}{
+		{database.C.Model(&models.Post{}).Where("fediverse_id IS NULL"), &stats.Posts},
+		{database.C.Model(&models.Post{}).Where("fediverse_id IS NULL AND created_at > ?", time.Now().Add(-24*time.Hour)), &stats.PostsToday},
+		{database.C.Model(&models.Post{}).Where("fediverse_id IS NOT NULL"), &stats.FediversePosts},
+		{database.C.Model(&models.Publisher{}).Where("type != ?", models.PublisherTypeFediverse), &stats.Publishers},
+		{database.C.Model(&models.Publisher{}).Where("type = ?", models.PublisherTypeFediverse), &stats.FediversePublishers},
+		{database.C.Model(&models.Publisher{}).Where("suspended_at IS NOT NULL"), &stats.SuspendedPublishers},
+		{database.C.Model(&models.Reaction{}), &stats.Reactions},
+		{database.C.Model(&models.Tag{}), &stats.Tags},
+		{database.C.Model(&models.Category{}), &stats.Categories},
+		{database.C.Model(&models.PostFlag{}).Where("status = ?", models.PostFlagPending), &stats.PendingReports},
+		{database.C.Model(&models.FediverseDelivery{}).Where("status = ?", models.FediverseDeliveryPending), &stats.PendingDeliveries},
+		{database.C.Model(&models.FediverseDelivery{}).Where("status = ?", models.FediverseDeliveryDead), &stats.DeadDeliveries},
+	}
+	for _, counter := range counters {
+		if err := counter.tx.Count(counter.out).Error; err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// RecountVoteTotals rebuilds the vote counters of posts and publishers from the reactions
+// It returns the number of posts and publishers were changed
+func RecountVoteTotals() (int64, int64, error) {
+	var posts, publishers int64
+	err := database.C.Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec(`UPDATE posts SET
+			total_upvote = counted.upvote,
+			total_downvote = counted.downvote
+		FROM (
+			SELECT posts.id,
+				COUNT(reactions.id) FILTER (WHERE reactions.attitude = ?) AS upvote,
+				COUNT(reactions.id) FILTER (WHERE reactions.attitude = ?) AS downvote
+			FROM posts LEFT JOIN reactions ON reactions.post_id = posts.id
+			GROUP BY posts.id
+		) AS counted
+		WHERE posts.id = counted.id AND (posts.total_upvote != counted.upvote OR posts.total_downvote != counted.downvote)`,
+			models.AttitudePositive, models.AttitudeNegative,
+		)
+		if result.Error != nil {
+			return result.Error
+		}
+		posts = result.RowsAffected
+
+		result = tx.Exec(`UPDATE publishers SET
+			total_upvote = counted.upvote,
+			total_downvote = counted.downvote
+		FROM (
+			SELECT publishers.id,
+				COALESCE(SUM(posts.total_upvote), 0) AS upvote,
+				COALESCE(SUM(posts.total_downvote), 0) AS downvote
+			FROM publishers LEFT JOIN posts ON posts.publisher_id = publishers.id AND posts.deleted_at IS NULL
+			GROUP BY publishers.id
+		) AS counted
+		WHERE publishers.id = counted.id AND (publishers.total_upvote != counted.upvote OR publishers.total_downvote != counted.downvote)`)
+		if result.Error != nil {
+			return result.Error
+		}
+		publishers = result.RowsAffected
+
+		return nil
+	})
+	return posts, publishers, err
+}
+
+func SuspendPublisher(publisher models.Publisher, reason string) (models.Publisher, error) {
+	if publisher.SuspendedAt != nil {
+		return publisher, fmt.Errorf("publisher was already suspended")
+	}
+	publisher.SuspendedAt = lo.ToPtr(time.Now())
+	publisher.SuspendReason = reason
+	err := database.C.Model(&publisher).Updates(map[string]any{
+		"suspended_at":   publisher.SuspendedAt,
+		"suspend_reason": publisher.SuspendReason,
+	}).Error
+	return publisher, err
+}
+
+func UnsuspendPublisher(publisher models.Publisher) (models.Publisher, error) {
+	if publisher.SuspendedAt == nil {
+		return publisher, fmt.Errorf("publisher was not suspended")
+	}
+	publisher.SuspendedAt = nil
+	publisher.SuspendReason = ""
+	err := database.C.Model(&publisher).Updates(map[string]any{
+		"suspended_at":   nil,
+		"suspend_reason": "",
+	}).Error
+	return publisher, err
+}
+
+// MergeTags moves the posts, subscriptions and mutes of the source tag to the target, and deletes the source
+func MergeTags(source, target models.Tag) error {
+	if source.ID == target.ID {
+		return fmt.Errorf("cannot merge a tag into itself")
+	}
+	return database.C.Transaction(func(tx *gorm.DB) error {
+		if err := mergeTaxonomy(tx, "post_tags", "tag_id", source.ID, target.ID); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&source).Error
+	})
+}
+
+// MergeCategories works like MergeTags but for the categories
+func MergeCategories(source, target models.Category) error {
+	if source.ID == target.ID {
+		return fmt.Errorf("cannot merge a category into itself")
+	}
+	return database.C.Transaction(func(tx *gorm.DB) error {
+		if err := mergeTaxonomy(tx, "post_categories", "category_id", source.ID, target.ID); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&source).Error
+	})
+}
+
+func mergeTaxonomy(tx *gorm.DB, table, column string, source, target uint) error {
+	if err := tx.Exec(
+		fmt.Sprintf("INSERT INTO %[1]s (post_id, %[2]s) SELECT post_id, ? FROM %[1]s WHERE %[2]s = ? ON CONFLICT DO NOTHING", table, column),
+		target, source,
+	).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s = ?", table, column), source).Error; err != nil {
+		return err
+	}
+
+	// The followers already subscribed the target don't need the subscription of the source anymore
+	if err := tx.Where(
+		fmt.Sprintf("%[1]s = ? AND follower_id IN (?)", column),
+		source,
+		tx.Model(&models.Subscription{}).Select("follower_id").Where(column+" = ?", target),
+	).Delete(&models.Subscription{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Subscription{}).Where(column+" = ?", source).Update(column, target).Error; err != nil {
+		return err
+	}
+
+	// Same as the subscriptions, the users already muted the target keep only that mute
+	if err := tx.Where(
+		fmt.Sprintf("%[1]s = ? AND account_id IN (?)", column),
+		source,
+		tx.Model(&models.Mute{}).Select("account_id").Where(column+" = ?", target),
+	).Delete(&models.Mute{}).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.Mute{}).Where(column+" = ?", source).Update(column, target).Error
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"github.com/goccy/go-json"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// NewAuditLog records a privileged action, the before and after can be nil when there is nothing to compare
+// Failing to write the audit log won't stop the action, it will be logged instead
+func NewAuditLog(actor uint, action, targetType string, targetId uint, before, after any, reason string) {
+	entry := models.AuditLog{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetId,
+		Before:     marshalAuditSnapshot(before),
+		After:      marshalAuditSnapshot(after),
+		Reason:     reason,
+		ActorID:    actor,
+	}
+	if err := database.C.Create(&entry).Error; err != nil {
+		log.Error().Err(err).Str("action", action).Uint("actor", actor).Msg("An error occurred when writing audit log...")
+	}
+}
+
+func marshalAuditSnapshot(snapshot any) []byte {
+	if snapshot == nil {
+		return nil
+	}
+	raw, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil
+	}
+	return raw
+}
+
+// FilterAuditLogWithTarget limits the audit logs to the ones acting on the specific target
+// The target id is ignored when it is zero, so all the targets in that type will be included
+func FilterAuditLogWithTarget(tx *gorm.DB, targetType string, targetId uint) *gorm.DB {
+	tx = tx.Where("target_type = ?", targetType)
+	if targetId > 0 {
+		tx = tx.Where("target_id = ?", targetId)
+	}
+	return tx
+}
+
+func CountAuditLogs(tx *gorm.DB) (int64, error) {
+	var count int64
+	if err := tx.Model(&models.AuditLog{}).Count(&count).Error; err != nil {
+		return count, err
+	}
+	return count, nil
+}
+
+func ListAuditLogs(tx *gorm.DB, take, offset int) ([]models.AuditLog, error) {
+	var items []models.AuditLog
+	if err := tx.
+		Order("created_at DESC").
+		Limit(take).Offset(offset).
+		Find(&items).Error; err != nil {
+		return items, err
+	}
+	return items, nil
+}
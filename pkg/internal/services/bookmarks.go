@@ -0,0 +1,143 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func FilterPostWithBookmarked(tx *gorm.DB, uid uint) *gorm.DB {
+	return tx.Where("id IN (?)", database.C.Model(&models.PostBookmark{}).Select("post_id").Where("account_id = ?", uid))
+}
+
+func BookmarkPost(item models.Post, uid uint) (models.PostBookmark, error) {
+	bookmark := models.PostBookmark{PostID: item.ID, AccountID: uid}
+	err := database.C.Where(bookmark).FirstOrCreate(&bookmark).Error
+	return bookmark, err
+}
+
+func UnbookmarkPost(item models.Post, uid uint) error {
+	tx := database.C.Unscoped().Where("post_id = ? AND account_id = ?", item.ID, uid).Delete(&models.PostBookmark{})
+	if tx.Error != nil {
+		return tx.Error
+	} else if tx.RowsAffected == 0 {
+		return fmt.Errorf("post was not bookmarked")
+	}
+	return nil
+}
+
+func ListPostCollections(uid uint) ([]models.PostCollection, error) {
+	var collections []models.PostCollection
+	err := database.C.Where("account_id = ?", uid).Order("created_at DESC").Find(&collections).Error
+	return collections, err
+}
+
+// GetPostCollection returns the collection which is public or owned by the user
+func GetPostCollection(id uint, uid *uint) (models.PostCollection, error) {
+	var collection models.PostCollection
+	tx := database.C.Where("id = ?", id)
+	if uid != nil {
+		tx = tx.Where("is_public = ? OR account_id = ?", true, *uid)
+	} else {
+		tx = tx.Where("is_public = ?", true)
+	}
+	err := tx.First(&collection).Error
+	return collection, err
+}
+
+func GetOwnedPostCollection(id uint, uid uint) (models.PostCollection, error) {
+	var collection models.PostCollection
+	err := database.C.Where("id = ? AND account_id = ?", id, uid).First(&collection).Error
+	return collection, err
+}
+
+func DeletePostCollection(collection models.PostCollection) error {
+	return database.C.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("collection_id = ?", collection.ID).Delete(&models.PostCollectionItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&collection).Error
+	})
+}
+
+// AddPostToCollection appends the post at the end of the collection
+func AddPostToCollection(collection models.PostCollection, item models.Post) (models.PostCollectionItem, error) {
+	var entry models.PostCollectionItem
+	if err := database.C.Where("collection_id = ? AND post_id = ?", collection.ID, item.ID).First(&entry).Error; err == nil {
+		return entry, fmt.Errorf("post already in the collection")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return entry, err
+	}
+
+	var position int
+	if err := database.C.Model(&models.PostCollectionItem{}).
+		Where("collection_id = ?", collection.ID).
+		Select("COALESCE(MAX(position), 0)").
+		Scan(&position).Error; err != nil {
+		return entry, err
+	}
+
+	entry = models.PostCollectionItem{
+		Position:     position + 1,
+		PostID:       item.ID,
+		CollectionID: collection.ID,
+	}
+	err := database.C.Create(&entry).Error
+	return entry, err
+}
+
+func RemovePostFromCollection(collection models.PostCollection, postId uint) error {
+	tx := database.C.Unscoped().Where("collection_id = ? AND post_id = ?", collection.ID, postId).Delete(&models.PostCollectionItem{})
+	if tx.Error != nil {
+		return tx.Error
+	} else if tx.RowsAffected == 0 {
+		return fmt.Errorf("post was not in the collection")
+	}
+	return nil
+}
+
+// ReorderPostCollection updates the position of the posts by the order of the given post id list
+// The posts which are not in the list will be put at the end with their original order
+func ReorderPostCollection(collection models.PostCollection, order []uint) error {
+	var entries []models.PostCollectionItem
+	if err := database.C.Where("collection_id = ?", collection.ID).Order("position ASC").Find(&entries).Error; err != nil {
+		return err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := lo.IndexOf(order, entries[i].PostID), lo.IndexOf(order, entries[j].PostID)
+		if a < 0 || b < 0 {
+			return a >= 0
+		}
+		return a < b
+	})
+
+	return database.C.Transaction(func(tx *gorm.DB) error {
+		for idx, entry := range entries {
+			if err := tx.Model(&entry).Update("position", idx+1).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func FilterPostWithCollection(tx *gorm.DB, collection models.PostCollection) *gorm.DB {
+	return tx.Where("id IN (?)", database.C.Model(&models.PostCollectionItem{}).Select("post_id").Where("collection_id = ?", collection.ID))
+}
+
+// GetPostCollectionOrder returns the order clause to sort the posts by their position in the collection
+func GetPostCollectionOrder(collection models.PostCollection) clause.OrderBy {
+	return clause.OrderBy{
+		Expression: clause.Expr{
+			SQL:  "(SELECT position FROM post_collection_items WHERE collection_id = ? AND post_id = posts.id AND deleted_at IS NULL) ASC",
+			Vars: []any{collection.ID},
+		},
+	}
+}
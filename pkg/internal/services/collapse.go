@@ -0,0 +1,198 @@
+package services
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
+	"github.com/spf13/viper"
+)
+
+// CollapsePolicy decides when the reported post will be collapsed automatically
+type CollapsePolicy struct {
+	Threshold      float64
+	MinViews       int64
+	MinReporters   int64
+	TrustWeighting bool
+	ReviewExpiry   time.Duration
+}
+
+// CollapsePolicyRule is a policy in the config, the fields not set fall back to the policy it overrides
+type CollapsePolicyRule struct {
+	Threshold      *float64       `mapstructure:"threshold"`
+	MinViews       *int64         `mapstructure:"min_views"`
+	MinReporters   *int64         `mapstructure:"min_reporters"`
+	TrustWeighting *bool          `mapstructure:"trust_weighting"`
+	ReviewExpiry   *time.Duration `mapstructure:"review_expiry"`
+}
+
+type collapsePolicyConfig struct {
+	CollapsePolicyRule `mapstructure:",squash"`
+
+	Realms     map[string]CollapsePolicyRule `mapstructure:"realms"`
+	Categories map[string]CollapsePolicyRule `mapstructure:"categories"`
+}
+
+var defaultCollapsePolicy = CollapsePolicy{
+	Threshold:    0.5,
+	MinViews:     3,
+	MinReporters: 1,
+}
+
+// GetCollapsePolicy picks the policy for the post, the realm rule is applied first and then the category rules
+// When the post is in multiple categories, the one with the strictest effective threshold will be used
+func GetCollapsePolicy(post models.Post) CollapsePolicy {
+	var config collapsePolicyConfig
+	if err := viper.UnmarshalKey("collapse", &config); err != nil {
+		log.Error().Err(err).Msg("An error occurred when loading collapse policy...")
+	}
+
+	policy := mergeCollapsePolicy(defaultCollapsePolicy, config.CollapsePolicyRule)
+	if post.RealmID != nil {
+		if rule, ok := config.Realms[strconv.Itoa(int(*post.RealmID))]; ok {
+			policy = mergeCollapsePolicy(policy, rule)
+		}
+	}
+
+	var categoryPolicy *CollapsePolicy
+	for _, category := range post.Categories {
+		rule, ok := config.Categories[category.Alias]
+		if !ok {
+			continue
+		}
+		merged := mergeCollapsePolicy(policy, rule)
+		if categoryPolicy == nil || merged.Threshold < categoryPolicy.Threshold {
+			categoryPolicy = &merged
+		}
+	}
+	if categoryPolicy != nil {
+		return *categoryPolicy
+	}
+
+	return policy
+}
+
+func mergeCollapsePolicy(base CollapsePolicy, rule CollapsePolicyRule) CollapsePolicy {
+	base.Threshold = lo.FromPtrOr(rule.Threshold, base.Threshold)
+	base.MinViews = lo.FromPtrOr(rule.MinViews, base.MinViews)
+	base.MinReporters = lo.FromPtrOr(rule.MinReporters, base.MinReporters)
+	base.TrustWeighting = lo.FromPtrOr(rule.TrustWeighting, base.TrustWeighting)
+	base.ReviewExpiry = lo.FromPtrOr(rule.ReviewExpiry, base.ReviewExpiry)
+	return base
+}
+
+type reporterHistory struct {
+	AccountID uint
+	Resolved  int64
+	Dismissed int64
+}
+
+// GetReporterTrust weights the reporters by their past reports
+// The reporter without history is fully trusted, every dismissed report lowers the trust
+func GetReporterTrust(accounts []uint) (map[uint]float64, error) {
+	var histories []reporterHistory
+	if err := database.C.Model(&models.PostFlag{}).
+		Select("account_id, "+
+			"COUNT(*) FILTER (WHERE status = ?) AS resolved, "+
+			"COUNT(*) FILTER (WHERE status = ?) AS dismissed",
+			models.PostFlagResolved, models.PostFlagDismissed).
+		Where("account_id IN ?", accounts).
+		Group("account_id").
+		Scan(&histories).Error; err != nil {
+		return nil, err
+	}
+
+	out := make(map[uint]float64, len(accounts))
+	for _, account := range accounts {
+		out[account] = 1
+	}
+	for _, history := range histories {
+		out[history.AccountID] = float64(1+history.Resolved) / float64(1+history.Resolved+history.Dismissed)
+	}
+	return out, nil
+}
+
+func FlagCalculateCollapseStatus(post models.Post) error {
+	if post.IsCollapsed {
+		return nil
+	}
+
+	policy := GetCollapsePolicy(post)
+	if post.TotalViews < policy.MinViews || post.TotalViews <= 0 {
+		return nil
+	}
+
+	// Only the pending reports are counted, the reviewed ones were already handled by the moderator
+	// After the review expired, the reports before it restored cannot collapse the post again
+	tx := database.C.Model(&models.PostFlag{}).Where("post_id = ? AND status = ?", post.ID, models.PostFlagPending)
+	if post.CollapseRestoredAt != nil {
+		tx = tx.Where("created_at > ?", *post.CollapseRestoredAt)
+	}
+	var reporters []uint
+	if err := tx.
+		Distinct("account_id").
+		Pluck("account_id", &reporters).Error; err != nil {
+		return err
+	}
+	if int64(len(reporters)) < policy.MinReporters {
+		return nil
+	}
+
+	score := float64(len(reporters))
+	if policy.TrustWeighting {
+		trust, err := GetReporterTrust(reporters)
+		if err != nil {
+			return err
+		}
+		score = lo.Sum(lo.Values(trust))
+	}
+	if score/float64(post.TotalViews) < policy.Threshold {
+		return nil
+	}
+
+	updates := map[string]any{"is_collapsed": true, "is_collapsed_by_reports": true}
+	if policy.ReviewExpiry > 0 {
+		updates["collapse_review_until"] = time.Now().Add(policy.ReviewExpiry)
+	}
+	return database.C.Model(&post).Updates(updates).Error
+}
+
+var expiredCollapseReviewLock sync.Mutex
+
+// HandleExpiredCollapseReviews restores the automatically collapsed posts nobody reviewed in time
+// The reports are kept pending, so they stay in the moderator queue, but they won't collapse the post again
+func HandleExpiredCollapseReviews() {
+	if !expiredCollapseReviewLock.TryLock() {
+		return
+	}
+	defer expiredCollapseReviewLock.Unlock()
+
+	var posts []models.Post
+	if err := database.C.
+		Where("is_collapsed = ? AND collapse_review_until <= ?", true, time.Now()).
+		Limit(100).
+		Find(&posts).Error; err != nil {
+		log.Error().Err(err).Msg("An error occurred when fetching expired collapse reviews...")
+		return
+	}
+	if len(posts) == 0 {
+		return
+	}
+
+	log.Debug().Int("count", len(posts)).Msg("Restoring posts with expired collapse review...")
+
+	for _, post := range posts {
+		if err := database.C.Model(&post).Updates(map[string]any{
+			"is_collapsed":            false,
+			"is_collapsed_by_reports": false,
+			"collapse_review_until":   nil,
+			"collapse_restored_at":    time.Now(),
+		}).Error; err != nil {
+			log.Error().Err(err).Uint("post", post.ID).Msg("An error occurred when restoring collapsed post...")
+		}
+	}
+}
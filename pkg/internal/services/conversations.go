@@ -2,33 +2,55 @@ package services
 
 import (
 	"fmt"
+
 	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
 	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/samber/lo"
 	"github.com/spf13/viper"
 )
 
-func GetConversation(start uint, offset, take int, order string, participants []uint) ([]models.Post, error) {
+// The max depth to walk through the reply chain, to prevent the recursive query from running forever
+const maxConversationDepth = 64
+
+// GetConversation returns the post and its replies recursively
+// Only the replies from the participants will be returned if participants is not empty
+// The depth limits how many levels of replies will be walked through
+// The order is the whole ORDER BY clause, including the direction of every column
+func GetConversation(start uint, offset, take int, order string, participants []uint, depth ...int) ([]models.Post, error) {
 	var posts []models.Post
 
 	tablePrefix := viper.GetString("database.prefix")
 	table := tablePrefix + "posts"
 
+	maxDepth := maxConversationDepth
+	if len(depth) > 0 && depth[0] < maxDepth {
+		maxDepth = depth[0]
+	}
+
+	participantsFilter := ""
+	args := []any{start, maxDepth}
+	if len(participants) > 0 {
+		participantsFilter = "AND p.publisher_id IN (?)"
+		args = append(args, participants)
+	}
+
 	result := database.C.Raw(fmt.Sprintf(
 		`
         WITH RECURSIVE conversation AS (
-            SELECT *
+            SELECT *, 0 AS depth
             FROM %s
-            WHERE id = ?
+            WHERE id = ? AND deleted_at IS NULL
 
             UNION ALL
 
-            SELECT p.*
+            SELECT p.*, c.depth + 1
             FROM %s p
-            INNER JOIN conversation c ON p.reply_id = c.id AND p.publisher_id IN (?)
+            INNER JOIN conversation c ON p.reply_id = c.id AND c.depth < ? AND p.deleted_at IS NULL %s
         )
-        SELECT * FROM conversation ORDER BY %s DESC OFFSET %d LIMIT %d`,
-		table, table, order, offset, take,
-	), start, participants).Scan(&posts)
+        SELECT * FROM conversation ORDER BY %s OFFSET %d LIMIT %d`,
+		table, table, participantsFilter, order, offset, take,
+	), args...).Scan(&posts)
 
 	// Check for errors
 	if result.Error != nil {
@@ -37,3 +59,98 @@ func GetConversation(start uint, offset, take int, order string, participants []
 
 	return posts, nil
 }
+
+// GetPostAncestors returns the id of the posts replied by the post
+// The root post comes first and the direct parent comes last
+func GetPostAncestors(start uint) ([]uint, error) {
+	var ids []uint
+
+	tablePrefix := viper.GetString("database.prefix")
+	table := tablePrefix + "posts"
+
+	result := database.C.Raw(fmt.Sprintf(
+		`
+        WITH RECURSIVE ancestors AS (
+            SELECT id, reply_id, 0 AS depth
+            FROM %s
+            WHERE id = ?
+
+            UNION ALL
+
+            SELECT p.id, p.reply_id, a.depth + 1
+            FROM %s p
+            INNER JOIN ancestors a ON p.id = a.reply_id AND a.depth < ? AND p.deleted_at IS NULL
+        )
+        SELECT id FROM ancestors WHERE id != ? ORDER BY depth DESC`,
+		table, table,
+	), start, maxConversationDepth, start).Scan(&ids)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return ids, nil
+}
+
+// GetPostThread returns the ancestors chain and the post with its nested replies
+// Every post goes through the UniversalPostFilter, the replies under an invisible post will be dropped
+func GetPostThread(c *fiber.Ctx, item models.Post, depth, take int, user *uint) ([]models.Post, models.Post, error) {
+	ancestorsId, err := GetPostAncestors(item.ID)
+	if err != nil {
+		return nil, item, err
+	}
+	// Keep the shallow replies when there are too many of them
+	conversation, err := GetConversation(item.ID, 0, take, "depth ASC, published_at DESC", nil, depth)
+	if err != nil {
+		return nil, item, err
+	}
+
+	ids := append(lo.Map(conversation, func(item models.Post, index int) uint {
+		return item.ID
+	}), ancestorsId...)
+	tx, err := UniversalPostFilter(c, database.C, UniversalPostFilterConfig{
+		ShowReply: true,
+	})
+	if err != nil {
+		return nil, item, err
+	}
+	tx = tx.Where("id IN ?", ids)
+	posts, err := ListPost(tx, -1, -1, "published_at ASC", user)
+	if err != nil {
+		return nil, item, err
+	}
+
+	mapping := lo.SliceToMap(posts, func(item models.Post) (uint, models.Post) {
+		return item.ID, item
+	})
+	root, ok := mapping[item.ID]
+	if !ok {
+		return nil, item, fmt.Errorf("post is not visible for you")
+	}
+
+	// The ancestors chain will stop at the first invisible post from the bottom
+	var ancestors []models.Post
+	for idx := len(ancestorsId) - 1; idx >= 0; idx-- {
+		ancestor, ok := mapping[ancestorsId[idx]]
+		if !ok {
+			break
+		}
+		ancestors = append([]models.Post{ancestor}, ancestors...)
+	}
+
+	children := make(map[uint][]models.Post)
+	for _, post := range posts {
+		if post.ReplyID != nil && post.ID != item.ID && !lo.Contains(ancestorsId, post.ID) {
+			children[*post.ReplyID] = append(children[*post.ReplyID], post)
+		}
+	}
+	var buildTree func(node models.Post) models.Post
+	buildTree = func(node models.Post) models.Post {
+		node.Replies = lo.Map(children[node.ID], func(item models.Post, index int) models.Post {
+			return buildTree(item)
+		})
+		return node
+	}
+
+	return ancestors, buildTree(root), nil
+}
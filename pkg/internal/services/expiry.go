@@ -0,0 +1,155 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/gap"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"git.solsynth.dev/hypernet/paperclip/pkg/filekit"
+	pproto "git.solsynth.dev/hypernet/paperclip/pkg/proto"
+	"github.com/goccy/go-json"
+	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+var expiredPostLock sync.Mutex
+
+// HandleExpiredPosts archives or deletes the posts after their PublishedUntil
+// It depends on the preference of the publisher, only the attachments of the deleted ones will be released
+func HandleExpiredPosts() {
+	if !expiredPostLock.TryLock() {
+		return
+	}
+	defer expiredPostLock.Unlock()
+
+	var posts []models.Post
+	if err := database.C.
+		Where("published_until <= ? AND expired_at IS NULL AND fediverse_id IS NULL", time.Now()).
+		Preload("Publisher").
+		Limit(100).
+		Find(&posts).Error; err != nil {
+		log.Error().Err(err).Msg("An error occurred when fetching expired posts...")
+		return
+	}
+	if len(posts) == 0 {
+		return
+	}
+
+	log.Debug().Int("count", len(posts)).Msg("Handling expired posts...")
+
+	for _, post := range posts {
+		if err := ExpirePost(post); err != nil {
+			log.Error().Err(err).Uint("post", post.ID).Msg("An error occurred when handling expired post...")
+		}
+	}
+}
+
+func ExpirePost(item models.Post) error {
+	// The archived post is no longer federated, send the deletion with the post before it
+	og := item
+
+	switch item.Publisher.ExpiredPostAction {
+	case models.ExpiredPostDelete:
+		if err := hardDeletePost(item); err != nil {
+			return err
+		}
+		releasePostAttachments(item)
+	default:
+		// The archived post can be restored by extending its PublishedUntil, so the attachments are kept
+		item.ExpiredAt = lo.ToPtr(time.Now())
+		if err := database.C.Model(&item).Update("expired_at", item.ExpiredAt).Error; err != nil {
+			return err
+		}
+	}
+
+	go FederatePost(og, nil, true)
+
+	if item.Publisher.AccountID != nil {
+		var title string
+		if val, ok := item.Body["title"].(string); ok && len(val) > 0 {
+			title = val
+		} else if val, ok := item.Body["content"].(string); ok {
+			title = TruncatePostContentShort(val)
+		}
+		err := NotifyPosterAccount(
+			item.Publisher,
+			item,
+			"Post expired",
+			fmt.Sprintf(
+				"Your post \"%s\" reached its expiry date and was %s.",
+				title,
+				lo.Ternary(item.Publisher.ExpiredPostAction == models.ExpiredPostDelete, "deleted", "archived"),
+			),
+			"interactive.expiry",
+		)
+		if err != nil {
+			log.Error().Err(err).Msg("An error occurred when notifying user...")
+		}
+	}
+
+	return nil
+}
+
+// hardDeletePost removes the post and the records depending on it permanently
+// The replies and reposts will be kept but no longer linked to it
+func hardDeletePost(item models.Post) error {
+	return database.C.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []any{&models.Reaction{}, &models.PostFlag{}, &models.PostRevision{}, &models.PostInsight{}, &models.PostView{}, &models.PostBookmark{}, &models.PostCollectionItem{}} {
+			if err := tx.Unscoped().Where("post_id = ?", item.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&item).Association("Tags").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Model(&item).Association("Categories").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Model(&item).Association("Mentions").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Post{}).Where("reply_id = ?", item.ID).Update("reply_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Post{}).Where("repost_id = ?", item.ID).Update("repost_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&item).Error
+	})
+}
+
+func releasePostAttachments(item models.Post) {
+	var body models.PostStoryBody
+	{
+		raw, _ := json.Marshal(item.Body)
+		json.Unmarshal(raw, &body)
+	}
+
+	attachments := body.Attachments
+	for _, field := range []string{"thumbnail", "video"} {
+		if dat, ok := item.Body[field].(string); ok && !strings.HasPrefix(dat, "http") {
+			attachments = append(attachments, dat)
+		}
+	}
+	if len(attachments) == 0 {
+		return
+	}
+
+	err := filekit.CountAttachmentUsage(gap.Nx, &pproto.UpdateUsageRequest{
+		Rid:   lo.Uniq(attachments),
+		Delta: -1,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("An error occurred when releasing post attachments...")
+	}
+}
+
+// FilterPostExpired lists the expired posts of the publisher, only the archived ones still exist
+func FilterPostExpired(tx *gorm.DB, publisher models.Publisher) *gorm.DB {
+	return tx.Where("publisher_id = ? AND published_until <= ?", publisher.ID, time.Now())
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"github.com/goccy/go-json"
+	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+const FediverseFriendTypeMastodon = "mastodon"
+
+// FediverseFriend is an instance configured in settings, we will pull its public timeline periodically
+type FediverseFriend struct {
+	ID        string `mapstructure:"id"`
+	URL       string `mapstructure:"url"`
+	Type      string `mapstructure:"type"`
+	BatchSize int    `mapstructure:"batch_size"`
+}
+
+type mastodonAccount struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	Note        string `json:"note"`
+	Avatar      string `json:"avatar"`
+	Header      string `json:"header"`
+	URL         string `json:"url"`
+	URI         string `json:"uri"`
+}
+
+type mastodonMediaAttachment struct {
+	Type        string  `json:"type"`
+	URL         string  `json:"url"`
+	Description *string `json:"description"`
+}
+
+type mastodonTag struct {
+	Name string `json:"name"`
+}
+
+type mastodonStatus struct {
+	ID               string                    `json:"id"`
+	URI              string                    `json:"uri"`
+	URL              *string                   `json:"url"`
+	CreatedAt        time.Time                 `json:"created_at"`
+	Content          string                    `json:"content"`
+	SpoilerText      string                    `json:"spoiler_text"`
+	Sensitive        bool                      `json:"sensitive"`
+	Language         *string                   `json:"language"`
+	Visibility       string                    `json:"visibility"`
+	InReplyToID      *string                   `json:"in_reply_to_id"`
+	Reblog           *mastodonStatus           `json:"reblog"`
+	Account          mastodonAccount           `json:"account"`
+	MediaAttachments []mastodonMediaAttachment `json:"media_attachments"`
+	Tags             []mastodonTag             `json:"tags"`
+}
+
+var fediverseFriendLock sync.Mutex
+
+// fediverseFriendClient is used for the instances configured by the admin, so it is not limited to the public addresses
+var fediverseFriendClient = &http.Client{Timeout: 10 * time.Second}
+
+// fediverseTimelineMaxSize caps the timeline response, a batch of statuses is far smaller than this
+const fediverseTimelineMaxSize = 4 << 20
+
+func GetFediverseFriends() []FediverseFriend {
+	var friends []FediverseFriend
+	if err := viper.UnmarshalKey("fediverse.friends", &friends); err != nil {
+		log.Error().Err(err).Msg("An error occurred when loading fediverse friends...")
+	}
+	return friends
+}
+
+// FetchFediverseFriendsTimeline pulls the public timelines of the fediverse friends
+// The statuses will be stored as read-only posts of the remote publishers
+func FetchFediverseFriendsTimeline() {
+	if !fediverseFriendLock.TryLock() {
+		return
+	}
+	defer fediverseFriendLock.Unlock()
+
+	for _, friend := range GetFediverseFriends() {
+		var err error
+		var count int
+		start := time.Now()
+		switch friend.Type {
+		case FediverseFriendTypeMastodon:
+			count, err = fetchMastodonTimeline(friend)
+		default:
+			err = fmt.Errorf("unsupported fediverse friend type %s", friend.Type)
+		}
+		if err != nil {
+			log.Error().Err(err).Str("friend", friend.ID).Msg("An error occurred when fetching fediverse timeline...")
+			continue
+		}
+		log.Debug().Str("friend", friend.ID).Int("count", count).Dur("elapsed", time.Since(start)).Msg("Fetched fediverse timeline.")
+	}
+}
+
+func fetchMastodonTimeline(friend FediverseFriend) (int, error) {
+	var cursor models.FediverseFriendCursor
+	if err := database.C.Where("friend_id = ?", friend.ID).First(&cursor).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+
+	statuses, err := fetchMastodonStatuses(friend, cursor.Cursor)
+	if err != nil {
+		return 0, err
+	}
+
+	var known []string
+	if err := database.C.Model(&models.Post{}).
+		Where("fediverse_id IN ?", lo.Map(statuses, func(item mastodonStatus, index int) string {
+			return item.URI
+		})).
+		Pluck("fediverse_id", &known).Error; err != nil {
+		return 0, err
+	}
+
+	var count int
+	for _, status := range selectMastodonStatuses(statuses, known) {
+		if imported, err := importMastodonStatus(friend, status); err != nil {
+			log.Warn().Err(err).Str("friend", friend.ID).Str("status", status.URI).Msg("An error occurred when importing fediverse status...")
+		} else if imported {
+			count++
+		}
+	}
+	// Mastodon returns the newest status first
+	if len(statuses) > 0 {
+		cursor.FriendID = friend.ID
+		cursor.Cursor = statuses[0].ID
+		if err := database.C.Save(&cursor).Error; err != nil {
+			log.Error().Err(err).Str("friend", friend.ID).Msg("An error occurred when saving fediverse timeline cursor...")
+		}
+	}
+
+	return count, nil
+}
+
+func fetchMastodonStatuses(friend FediverseFriend, cursor string) ([]mastodonStatus, error) {
+	query := url.Values{}
+	query.Set("local", "true")
+	query.Set("limit", fmt.Sprint(lo.Ternary(friend.BatchSize > 0, friend.BatchSize, 20)))
+	if len(cursor) > 0 {
+		query.Set("since_id", cursor)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(friend.URL, "/")+"/api/v1/timelines/public?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := fediverseFriendClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch timeline: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch timeline: status code %d", resp.StatusCode)
+	}
+
+	var statuses []mastodonStatus
+	if err := json.NewDecoder(io.LimitReader(resp.Body, fediverseTimelineMaxSize)).Decode(&statuses); err != nil {
+		return nil, fmt.Errorf("failed to decode timeline: %v", err)
+	}
+	return statuses, nil
+}
+
+// selectMastodonStatuses picks the statuses should be imported
+// Only the public posts on the top level are imported, and the known ones are skipped
+func selectMastodonStatuses(statuses []mastodonStatus, known []string) []mastodonStatus {
+	seen := lo.SliceToMap(known, func(item string) (string, bool) {
+		return item, true
+	})
+
+	var out []mastodonStatus
+	for _, status := range statuses {
+		if status.Reblog != nil || status.InReplyToID != nil || status.Visibility != "public" {
+			continue
+		}
+		if len(status.URI) == 0 || seen[status.URI] {
+			continue
+		}
+		seen[status.URI] = true
+		out = append(out, status)
+	}
+	return out
+}
+
+func importMastodonStatus(friend FediverseFriend, status mastodonStatus) (bool, error) {
+	if len(status.URI) == 0 {
+		return false, fmt.Errorf("status has no uri")
+	}
+	var count int64
+	if err := database.C.Model(&models.Post{}).Where("fediverse_id = ?", status.URI).Count(&count).Error; err != nil {
+		return false, err
+	} else if count > 0 {
+		return false, nil
+	}
+
+	author, item := buildMastodonStatusPost(friend, status)
+	publisher, err := EnsureFediversePublisher(author)
+	if err != nil {
+		return false, err
+	}
+
+	item.PublisherID = publisher.ID
+	if item, err = EnsurePostCategoriesAndTags(item); err != nil {
+		return false, err
+	}
+
+	if err := database.C.Save(&item).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// buildMastodonStatusPost maps the status to the post and its remote publisher
+// The publisher of the post is left empty, it is filled after the publisher record is ensured
+func buildMastodonStatusPost(friend FediverseFriend, status mastodonStatus) (models.Publisher, models.Post) {
+	// The host of the handle comes from the actor, the web domain of the instance can be a different one
+	actorID := lo.Ternary(len(status.Account.URI) > 0, status.Account.URI, status.Account.URL)
+	host := friend.URL
+	if uri, err := url.Parse(actorID); err == nil && len(uri.Host) > 0 {
+		host = uri.Host
+	} else if uri, err := url.Parse(friend.URL); err == nil {
+		host = uri.Host
+	}
+	publisher := models.Publisher{
+		Name:        fmt.Sprintf("%s@%s", status.Account.Username, host),
+		Nick:        lo.Ternary(len(status.Account.DisplayName) > 0, status.Account.DisplayName, status.Account.Username),
+		Description: ConvertActivityPubContent(status.Account.Note),
+		Avatar:      status.Account.Avatar,
+		Banner:      status.Account.Header,
+		FediverseID: &actorID,
+	}
+
+	content := ConvertActivityPubContent(status.Content)
+	body := map[string]any{
+		"content":       content,
+		"fediverse_url": lo.FromPtrOr(status.URL, status.URI),
+		"fediverse_attachments": lo.Map(status.MediaAttachments, func(item mastodonMediaAttachment, index int) map[string]any {
+			return map[string]any{
+				"type": item.Type,
+				"url":  item.URL,
+				"alt":  item.Description,
+			}
+		}),
+		"fediverse_sensitive": status.Sensitive,
+	}
+	if len(status.SpoilerText) > 0 {
+		body["title"] = status.SpoilerText
+	}
+
+	item := models.Post{
+		Type:        models.PostTypeStory,
+		Body:        body,
+		Language:    lo.TernaryF(status.Language != nil, func() string { return *status.Language }, func() string { return DetectLanguage(content) }),
+		Visibility:  models.PostVisibilityAll,
+		PublishedAt: lo.ToPtr(status.CreatedAt),
+		FediverseID: &status.URI,
+		Tags: lo.Map(status.Tags, func(item mastodonTag, index int) models.Tag {
+			return models.Tag{Alias: strings.ToLower(item.Name), Name: item.Name}
+		}),
+	}
+
+	return publisher, item
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"github.com/go-ap/activitypub"
+	"github.com/goccy/go-json"
+	"github.com/samber/lo"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+var activityPubActorTypes = activitypub.ActivityVocabularyTypes{
+	activitypub.PersonType,
+	activitypub.ServiceType,
+	activitypub.OrganizationType,
+	activitypub.GroupType,
+	activitypub.ApplicationType,
+}
+
+type webfingerLink struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type"`
+	Href string `json:"href"`
+}
+
+// IsFediverseProbe tells is the search term an url or a handle that can be resolved from other instances
+func IsFediverseProbe(probe string) bool {
+	if strings.HasPrefix(probe, "https://") {
+		return true
+	}
+	parts := strings.Split(strings.TrimPrefix(probe, "@"), "@")
+	return len(parts) == 2 && len(parts[0]) > 0 && strings.Contains(parts[1], ".") && !strings.ContainsAny(probe, " \t\n")
+}
+
+// ResolveFediverseProbe finds the post or publisher that the url or handle is pointing to
+// The remote actor and note will be imported when they are not stored before
+func ResolveFediverseProbe(probe string) (*models.Post, *models.Publisher, error) {
+	if strings.HasPrefix(probe, "https://") {
+		return ResolveFediverseURL(probe)
+	}
+	publisher, err := ResolveFediverseHandle(probe)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, &publisher, nil
+}
+
+// ResolveFediverseHandle finds the publisher by the handle like @user@host via WebFinger
+func ResolveFediverseHandle(handle string) (models.Publisher, error) {
+	var publisher models.Publisher
+
+	parts := strings.SplitN(strings.TrimPrefix(handle, "@"), "@", 2)
+	if len(parts) != 2 {
+		return publisher, fmt.Errorf("invalid handle %s", handle)
+	}
+	username, host := parts[0], strings.ToLower(parts[1])
+
+	if baseUrl, err := url.Parse(viper.GetString("activitypub_base_url")); err == nil && baseUrl.Host == host {
+		err := database.C.Where("name = ? AND type != ?", username, models.PublisherTypeFediverse).First(&publisher).Error
+		return publisher, err
+	}
+	if err := database.C.Where("name = ? AND type = ?", username+"@"+host, models.PublisherTypeFediverse).First(&publisher).Error; err == nil {
+		return publisher, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return publisher, err
+	}
+
+	query := url.Values{}
+	query.Set("resource", fmt.Sprintf("acct:%s@%s", username, host))
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://%s/.well-known/webfinger?%s", host, query.Encode()), nil)
+	if err != nil {
+		return publisher, err
+	}
+	req.Header.Set("Accept", "application/jrd+json")
+	resp, err := activityPubClient.Do(req)
+	if err != nil {
+		return publisher, fmt.Errorf("failed to lookup %s: %v", handle, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return publisher, fmt.Errorf("failed to lookup %s: status code %d", handle, resp.StatusCode)
+	}
+
+	var webfinger struct {
+		Links []webfingerLink `json:"links"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, activityPubMaxResponseSize)).Decode(&webfinger); err != nil {
+		return publisher, fmt.Errorf("failed to lookup %s: %v", handle, err)
+	}
+	link, ok := lo.Find(webfinger.Links, func(item webfingerLink) bool {
+		return item.Rel == "self" && (strings.Contains(item.Type, "activity+json") || strings.Contains(item.Type, "ld+json"))
+	})
+	if !ok {
+		return publisher, fmt.Errorf("%s has no activitypub actor", handle)
+	}
+
+	actor, err := FetchActivityPubActor(link.Href)
+	if err != nil {
+		return publisher, err
+	}
+	return GetFediversePublisher(actor)
+}
+
+// ResolveFediverseURL finds the post or publisher by its url
+// Both the object IRI and the web page url are supported because most of the implementations support content negotiation
+func ResolveFediverseURL(uri string) (*models.Post, *models.Publisher, error) {
+	if id, ok := ParseActivityPostID(uri); ok {
+		var item models.Post
+		if err := database.C.Where("id = ?", id).First(&item).Error; err != nil {
+			return nil, nil, err
+		}
+		return &item, nil, nil
+	}
+	if name, ok := ParseActivityPubPublisherName(uri); ok {
+		var publisher models.Publisher
+		if err := database.C.Where("name = ? AND type != ?", name, models.PublisherTypeFediverse).First(&publisher).Error; err != nil {
+			return nil, nil, err
+		}
+		return nil, &publisher, nil
+	}
+
+	var item models.Post
+	if err := database.C.Where("fediverse_id = ?", uri).First(&item).Error; err == nil {
+		return &item, nil, nil
+	}
+	var publisher models.Publisher
+	if err := database.C.Where("fediverse_id = ?", uri).First(&publisher).Error; err == nil {
+		return nil, &publisher, nil
+	}
+
+	object, err := FetchActivityPubObject(uri)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The object must be served by its own origin, otherwise any server can claim objects of the others
+	if !IsSameActivityPubOrigin(uri, object.GetLink().String()) {
+		return nil, nil, fmt.Errorf("the object id does not match the requested origin")
+	}
+
+	if activityPubActorTypes.Contains(object.GetType()) {
+		actor, err := activitypub.ToActor(object)
+		if err != nil {
+			return nil, nil, err
+		}
+		publisher, err := GetFediversePublisher(actor)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, &publisher, nil
+	}
+
+	note, err := activitypub.ToObject(object)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to parse object: %v", err)
+	}
+	item, err = ImportActivityPubNote(note)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &item, nil, nil
+}
+
+// ImportActivityPubNote stores the public note from other instances as a read-only post
+// The note will be linked as a reply when the replied post is known by this instance
+func ImportActivityPubNote(note *activitypub.Object) (models.Post, error) {
+	var item models.Post
+	if note.Type != activitypub.NoteType && note.Type != activitypub.ArticleType && note.Type != activitypub.QuestionType {
+		return item, fmt.Errorf("unsupported object type %s", note.Type)
+	}
+	if !IsActivityPubPublic(note) {
+		return item, fmt.Errorf("the object is not public")
+	}
+	if err := database.C.Where("fediverse_id = ?", note.ID.String()).First(&item).Error; err == nil {
+		return item, nil
+	}
+	if note.AttributedTo == nil {
+		return item, fmt.Errorf("the object has no author")
+	}
+	if !IsSameActivityPubOrigin(note.ID.String(), note.AttributedTo.GetLink().String()) {
+		return item, fmt.Errorf("the object and its author are not on the same origin")
+	}
+
+	actor, err := FetchActivityPubActor(note.AttributedTo.GetLink().String())
+	if err != nil {
+		return item, err
+	}
+	publisher, err := GetFediversePublisher(actor)
+	if err != nil {
+		return item, err
+	}
+
+	item = BuildActivityPubPost(note, publisher)
+	if note.InReplyTo != nil {
+		replyIRI := note.InReplyTo.GetLink().String()
+		var op models.Post
+		if id, ok := ParseActivityPostID(replyIRI); ok {
+			if err := database.C.Where("id = ?", id).Select("id").First(&op).Error; err == nil {
+				item.ReplyID = &op.ID
+			}
+		} else if err := database.C.Where("fediverse_id = ?", replyIRI).First(&op).Error; err == nil {
+			item.ReplyID = &op.ID
+		}
+	}
+
+	if err := database.C.Save(&item).Error; err != nil {
+		return item, err
+	}
+	return item, nil
+}
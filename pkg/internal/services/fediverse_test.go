@@ -0,0 +1,183 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+)
+
+const mastodonTimelineFixture = `[
+	{
+		"id": "103",
+		"uri": "https://mastodon.example/users/alice/statuses/103",
+		"url": "https://mastodon.example/@alice/103",
+		"created_at": "2024-05-01T10:00:00.000Z",
+		"content": "<p>Hello<br>fediverse</p><p>Second &amp; last</p>",
+		"spoiler_text": "Greetings",
+		"sensitive": true,
+		"language": "en",
+		"visibility": "public",
+		"account": {
+			"id": "1",
+			"username": "alice",
+			"display_name": "Alice",
+			"note": "<p>Just a test</p>",
+			"avatar": "https://mastodon.example/avatars/alice.png",
+			"header": "https://mastodon.example/headers/alice.png",
+			"url": "https://mastodon.example/@alice",
+			"uri": "https://mastodon.example/users/alice"
+		},
+		"media_attachments": [
+			{"type": "image", "url": "https://mastodon.example/media/1.png", "description": "A cat"}
+		],
+		"tags": [{"name": "GoLang"}]
+	},
+	{
+		"id": "102",
+		"uri": "https://mastodon.example/users/bob/statuses/102",
+		"created_at": "2024-05-01T09:00:00.000Z",
+		"content": "<p>Known already</p>",
+		"language": "en",
+		"visibility": "public",
+		"account": {"id": "2", "username": "bob", "uri": "https://mastodon.example/users/bob"}
+	},
+	{
+		"id": "101",
+		"uri": "https://mastodon.example/users/bob/statuses/101",
+		"created_at": "2024-05-01T08:00:00.000Z",
+		"content": "<p>A reply</p>",
+		"language": "en",
+		"visibility": "public",
+		"in_reply_to_id": "100",
+		"account": {"id": "2", "username": "bob", "uri": "https://mastodon.example/users/bob"}
+	},
+	{
+		"id": "100",
+		"uri": "https://mastodon.example/users/bob/statuses/100",
+		"created_at": "2024-05-01T07:00:00.000Z",
+		"content": "<p>Followers only</p>",
+		"language": "en",
+		"visibility": "private",
+		"account": {"id": "2", "username": "bob", "uri": "https://mastodon.example/users/bob"}
+	},
+	{
+		"id": "99",
+		"uri": "https://mastodon.example/users/alice/statuses/103",
+		"created_at": "2024-05-01T10:00:00.000Z",
+		"content": "<p>Hello again</p>",
+		"language": "en",
+		"visibility": "public",
+		"account": {"id": "1", "username": "alice", "uri": "https://mastodon.example/users/alice"}
+	}
+]`
+
+func newFakeMastodon(t *testing.T) (*httptest.Server, *http.Request) {
+	t.Helper()
+	var last http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		last = *r
+		if r.URL.Path != "/api/v1/timelines/public" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(mastodonTimelineFixture))
+	}))
+	t.Cleanup(server.Close)
+	return server, &last
+}
+
+func TestFetchMastodonStatuses(t *testing.T) {
+	server, last := newFakeMastodon(t)
+	friend := FediverseFriend{ID: "example", URL: server.URL + "/", Type: FediverseFriendTypeMastodon, BatchSize: 40}
+
+	statuses, err := fetchMastodonStatuses(friend, "98")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(statuses) != 5 {
+		t.Fatalf("expected 5 statuses, got %d", len(statuses))
+	}
+
+	query := last.URL.Query()
+	if query.Get("local") != "true" || query.Get("limit") != "40" || query.Get("since_id") != "98" {
+		t.Errorf("unexpected timeline query %q", last.URL.RawQuery)
+	}
+
+	if _, err := fetchMastodonStatuses(FediverseFriend{ID: "broken", URL: server.URL + "/missing"}, ""); err == nil {
+		t.Error("expected error for the non-ok status code")
+	}
+}
+
+func TestSelectMastodonStatuses(t *testing.T) {
+	server, _ := newFakeMastodon(t)
+	statuses, err := fetchMastodonStatuses(FediverseFriend{ID: "example", URL: server.URL}, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	selected := selectMastodonStatuses(statuses, []string{"https://mastodon.example/users/bob/statuses/102"})
+	if len(selected) != 1 {
+		t.Fatalf("expected 1 status to import, got %d", len(selected))
+	}
+	// The reply, the private one, the known one and the duplicated uri are skipped
+	if selected[0].ID != "103" {
+		t.Errorf("expected status 103 to be selected, got %s", selected[0].ID)
+	}
+}
+
+func TestBuildMastodonStatusPost(t *testing.T) {
+	server, _ := newFakeMastodon(t)
+	friend := FediverseFriend{ID: "example", URL: server.URL}
+	statuses, err := fetchMastodonStatuses(friend, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	publisher, item := buildMastodonStatusPost(friend, statuses[0])
+
+	// The handle takes the host of the actor instead of the friend url
+	if publisher.Name != "alice@mastodon.example" {
+		t.Errorf("unexpected publisher name %s", publisher.Name)
+	}
+	if publisher.Nick != "Alice" || publisher.Description != "Just a test" {
+		t.Errorf("unexpected publisher profile %q / %q", publisher.Nick, publisher.Description)
+	}
+	if publisher.FediverseID == nil || *publisher.FediverseID != "https://mastodon.example/users/alice" {
+		t.Errorf("unexpected publisher fediverse id %v", publisher.FediverseID)
+	}
+
+	if item.Type != models.PostTypeStory || item.Visibility != models.PostVisibilityAll {
+		t.Errorf("unexpected post type %s or visibility %d", item.Type, item.Visibility)
+	}
+	if item.FediverseID == nil || *item.FediverseID != statuses[0].URI {
+		t.Errorf("unexpected post fediverse id %v", item.FediverseID)
+	}
+	if item.LockedAt != nil {
+		t.Error("imported post should not be locked")
+	}
+	if item.Language != "en" {
+		t.Errorf("unexpected language %s", item.Language)
+	}
+	if item.PublishedAt == nil || !item.PublishedAt.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected published at %v", item.PublishedAt)
+	}
+	if content := item.Body["content"]; content != "Hello\nfediverse\n\nSecond & last" {
+		t.Errorf("unexpected content %q", content)
+	}
+	if title := item.Body["title"]; title != "Greetings" {
+		t.Errorf("unexpected title %q", title)
+	}
+	if url := item.Body["fediverse_url"]; url != "https://mastodon.example/@alice/103" {
+		t.Errorf("unexpected fediverse url %q", url)
+	}
+	if attachments, ok := item.Body["fediverse_attachments"].([]map[string]any); !ok || len(attachments) != 1 || attachments[0]["url"] != "https://mastodon.example/media/1.png" {
+		t.Errorf("unexpected attachments %v", item.Body["fediverse_attachments"])
+	}
+	if len(item.Tags) != 1 || item.Tags[0].Alias != "golang" || item.Tags[0].Name != "GoLang" {
+		t.Errorf("unexpected tags %v", item.Tags)
+	}
+}
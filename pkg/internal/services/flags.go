@@ -2,17 +2,27 @@ package services
 
 import (
 	"fmt"
+	"time"
 
 	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/gap"
 	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"git.solsynth.dev/hypernet/passport/pkg/authkit"
+	"git.solsynth.dev/hypernet/pusher/pkg/pushkit"
+	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
 )
 
-func NewFlag(post models.Post, account uint) (models.PostFlag, error) {
+func NewFlag(post models.Post, account uint, reason, note string) (models.PostFlag, error) {
 	var flag models.PostFlag
-	if err := database.C.Where("post_id = ? AND account_id = ?", post.ID, account).First(&flag).Error; err == nil {
+	if err := database.C.Where("post_id = ? AND account_id = ? AND status = ?", post.ID, account, models.PostFlagPending).First(&flag).Error; err == nil {
 		return flag, fmt.Errorf("flag already exists")
 	}
 	flag = models.PostFlag{
+		Reason:    reason,
+		Note:      note,
+		Status:    models.PostFlagPending,
 		PostID:    post.ID,
 		AccountID: account,
 	}
@@ -25,19 +35,186 @@ func NewFlag(post models.Post, account uint) (models.PostFlag, error) {
 	return flag, nil
 }
 
-func FlagCalculateCollapseStatus(post models.Post) error {
-	if post.TotalViews <= 2 {
+// PostFlagQueueItem is the reports of a post waiting for review
+type PostFlagQueueItem struct {
+	Post           models.Post      `json:"post"`
+	Count          int64            `json:"count"`
+	Reasons        map[string]int64 `json:"reasons"`
+	LastReportedAt time.Time        `json:"last_reported_at"`
+}
+
+func CountPostFlagQueue(status string) (int64, error) {
+	var count int64
+	err := database.C.Model(&models.PostFlag{}).
+		Where("status = ?", status).
+		Distinct("post_id").
+		Count(&count).Error
+	return count, err
+}
+
+type postFlagGroup struct {
+	PostID         uint
+	Count          int64
+	LastReportedAt time.Time
+}
+
+// ListPostFlagQueue lists the reports grouped by post, the post has the most reports comes first
+func ListPostFlagQueue(status string, take, offset int) ([]PostFlagQueueItem, error) {
+	var groups []postFlagGroup
+	if err := database.C.Model(&models.PostFlag{}).
+		Select("post_id, COUNT(*) AS count, MAX(created_at) AS last_reported_at").
+		Where("status = ?", status).
+		Group("post_id").
+		Order("count DESC, last_reported_at DESC").
+		Limit(take).Offset(offset).
+		Scan(&groups).Error; err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return []PostFlagQueueItem{}, nil
+	}
+
+	postIds := lo.Map(groups, func(item postFlagGroup, index int) uint {
+		return item.PostID
+	})
+
+	var posts []models.Post
+	if err := PreloadGeneral(database.C.Unscoped()).Where("id IN ?", postIds).Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	postsMap := lo.SliceToMap(posts, func(item models.Post) (uint, models.Post) {
+		return item.ID, item
+	})
+
+	var reasons []struct {
+		PostID uint
+		Reason string
+		Count  int64
+	}
+	if err := database.C.Model(&models.PostFlag{}).
+		Select("post_id, reason, COUNT(*) AS count").
+		Where("status = ? AND post_id IN ?", status, postIds).
+		Group("post_id, reason").
+		Scan(&reasons).Error; err != nil {
+		return nil, err
+	}
+
+	var out []PostFlagQueueItem
+	for _, group := range groups {
+		item := PostFlagQueueItem{
+			Post:           postsMap[group.PostID],
+			Count:          group.Count,
+			Reasons:        make(map[string]int64),
+			LastReportedAt: group.LastReportedAt,
+		}
+		for _, reason := range reasons {
+			if reason.PostID == group.PostID {
+				item.Reasons[reason.Reason] = reason.Count
+			}
+		}
+		out = append(out, item)
+	}
+
+	return out, nil
+}
+
+func ListPostFlags(post models.Post, status string) ([]models.PostFlag, error) {
+	var flags []models.PostFlag
+	err := database.C.Where("post_id = ? AND status = ?", post.ID, status).Order("created_at DESC").Find(&flags).Error
+	return flags, err
+}
+
+// ResolvePostFlags applies the outcome to the post and closes all the pending reports of it
+// Dismissing the reports means the post is fine, so the collapse caused by the reports will be reverted
+// The reports on a deleted post are only closed, there is nothing left to apply the outcome to
+func ResolvePostFlags(post models.Post, moderator uint, outcome string, dismiss bool) ([]models.PostFlag, error) {
+	flags, err := ListPostFlags(post, models.PostFlagPending)
+	if err != nil {
+		return nil, err
+	} else if len(flags) == 0 {
+		return nil, fmt.Errorf("post has no pending reports")
+	}
+
+	status := lo.Ternary(dismiss, models.PostFlagDismissed, models.PostFlagResolved)
+	if dismiss {
+		outcome = models.PostFlagOutcomeNone
+	}
+
+	err = database.C.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.PostFlag{}).
+			Where("post_id = ? AND status = ?", post.ID, models.PostFlagPending).
+			Updates(map[string]any{
+				"status":       status,
+				"moderator_id": moderator,
+				"outcome":      outcome,
+				"resolved_at":  time.Now(),
+			}).Error; err != nil {
+			return err
+		}
+
+		if post.DeletedAt.Valid {
+			return nil
+		}
+
+		switch outcome {
+		case models.PostFlagOutcomeCollapse:
+			return tx.Model(&post).Updates(map[string]any{
+				"is_collapsed":            true,
+				"is_collapsed_by_reports": false,
+				"collapse_review_until":   nil,
+			}).Error
+		case models.PostFlagOutcomeLock:
+			return tx.Model(&post).Updates(map[string]any{
+				"locked_at":           time.Now(),
+				"lock_reason":         "Locked after being reported",
+				"locked_by_moderator": true,
+			}).Error
+		case models.PostFlagOutcomeNone:
+			// The post collapsed by the moderator for other reasons stays collapsed
+			if dismiss && post.IsCollapsedByReports {
+				return tx.Model(&post).Updates(map[string]any{
+					"is_collapsed":            false,
+					"is_collapsed_by_reports": false,
+					"collapse_review_until":   nil,
+				}).Error
+			}
+		}
 		return nil
+	})
+	if err != nil {
+		return flags, err
+	}
+
+	// Deleting has its own side effects, so it runs outside the transaction after the reports are closed
+	if outcome == models.PostFlagOutcomeDelete && !post.DeletedAt.Valid {
+		if err := DeletePost(post); err != nil {
+			return flags, err
+		}
 	}
 
-	collapseLimit := 0.5
+	go notifyPostFlagReporters(post, flags, status)
 
-	var flagCount int64
-	if err := database.C.Model(&models.PostFlag{}).Where("post_id = ?", post.ID).Count(&flagCount).Error; err != nil {
-		return err
+	return flags, nil
+}
+
+func notifyPostFlagReporters(post models.Post, flags []models.PostFlag, status string) {
+	reporters := lo.Uniq(lo.Map(flags, func(item models.PostFlag, index int) uint64 {
+		return uint64(item.AccountID)
+	}))
+
+	body := "Thanks for your report, the moderator has reviewed it and taken action on the post."
+	if status == models.PostFlagDismissed {
+		body = "Thanks for your report, the moderator has reviewed it and found the post does not break the rules."
 	}
-	if float64(flagCount)/float64(post.TotalViews) >= collapseLimit {
-		return database.C.Model(&post).Update("is_collapsed", true).Error
+
+	err := authkit.NotifyUserBatch(gap.Nx, reporters, pushkit.Notification{
+		Topic:    "interactive.report",
+		Title:    "Your report has been reviewed",
+		Subtitle: fmt.Sprintf("Report on post #%d", post.ID),
+		Body:     body,
+		Priority: 3,
+	})
+	if err != nil {
+		log.Error().Err(err).Uint("post", post.ID).Msg("An error occurred when notifying reporters...")
 	}
-	return nil
 }
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"github.com/samber/lo"
+)
+
+// The hashtags longer than this will be ignored
+const maxHashtagLength = 64
+
+// The hashtag must not follow a latin letter, digit or slash, so the url fragments and things like C# are skipped
+// But it can follow the CJK characters directly, because there are no spaces between the words in those languages
+// The optional closing mark allows the Weibo style #topic# to mark the end of the hashtag in CJK sentences
+var hashtagRegex = regexp.MustCompile(`(?:^|[^\p{Latin}\p{N}_&/#])#([\p{L}\p{M}\p{N}_]+)#?`)
+
+// The lines starting with # are rendered as headings, and the code is not a part of the sentences
+var (
+	headingLineRegex = regexp.MustCompile(`^ {0,3}#`)
+	codeFenceRegex   = regexp.MustCompile("^ {0,3}(```|~~~)")
+	codeSpanRegex    = regexp.MustCompile("``[^`]*``|`[^`]*`")
+)
+
+// stripHashtagIgnoredText removes the headings, the code blocks and the code spans from the markdown text
+func stripHashtagIgnoredText(text string) string {
+	var lines []string
+	var inFence bool
+	for _, line := range strings.Split(text, "\n") {
+		if codeFenceRegex.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence || headingLineRegex.MatchString(line) {
+			continue
+		}
+		lines = append(lines, codeSpanRegex.ReplaceAllString(line, " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ExtractPostHashtags returns the hashtags in the post content and description
+func ExtractPostHashtags(item models.Post) []models.Tag {
+	var tags []models.Tag
+	for _, key := range []string{"content", "description"} {
+		text, ok := item.Body[key].(string)
+		if !ok {
+			continue
+		}
+		for _, match := range hashtagRegex.FindAllStringSubmatch(stripHashtagIgnoredText(text), -1) {
+			name := match[1]
+			if utf8.RuneCountInString(name) > maxHashtagLength {
+				continue
+			}
+			// Skip the numbers like #1 which are usually not a hashtag
+			if strings.Trim(name, "0123456789") == "" {
+				continue
+			}
+			tags = append(tags, models.Tag{Alias: strings.ToLower(name), Name: name})
+		}
+	}
+	return lo.UniqBy(tags, func(item models.Tag) string {
+		return item.Alias
+	})
+}
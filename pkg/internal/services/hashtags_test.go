@@ -0,0 +1,57 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+)
+
+func TestExtractPostHashtags(t *testing.T) {
+	cases := []struct {
+		name        string
+		content     string
+		description string
+		expected    []string
+	}{
+		{name: "latin", content: "Learning go today #golang #Rust_lang", expected: []string{"golang", "Rust_lang"}},
+		{name: "accented", content: "Bonjour #café et #naïve", expected: []string{"café", "naïve"}},
+		{name: "chinese without spaces", content: "今天学习了#编程，很开心", expected: []string{"编程"}},
+		{name: "weibo style", content: "参加了#周末活动#很有趣", expected: []string{"周末活动"}},
+		{name: "japanese", content: "今日は#プログラミング の日", expected: []string{"プログラミング"}},
+		{name: "korean", content: "오늘은 #한국어 공부", expected: []string{"한국어"}},
+		{name: "url fragment", content: "See https://example.com/page#section for more", expected: nil},
+		{name: "after latin letter", content: "Written in C# and F#", expected: nil},
+		{name: "html entity", content: "Tom &#38; Jerry", expected: nil},
+		{name: "numbers only", content: "Issue #1234 is fixed", expected: nil},
+		{name: "too long", content: "Nope #" + strings.Repeat("a", maxHashtagLength+1), expected: nil},
+		{name: "case insensitive duplicates", content: "Fun with #Golang, really #golang", expected: []string{"Golang"}},
+		{name: "heading", content: "#Heading\nSome text about #golang", expected: []string{"golang"}},
+		{name: "indented heading", content: "Intro\n  ## Heading with #tag\nBody", expected: nil},
+		{name: "inline code", content: "Run `echo #notatag` and ``#nope`` then #yes", expected: []string{"yes"}},
+		{name: "fenced code", content: "Look:\n```sh\n# comment\necho #notatag\n```\nDone #yes", expected: []string{"yes"}},
+		{name: "tilde fence", content: "~~~\n#notatag\n~~~\nDone #yes", expected: []string{"yes"}},
+		{name: "description", content: "Nothing here", description: "About #travel", expected: []string{"travel"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			body := map[string]any{"content": c.content}
+			if len(c.description) > 0 {
+				body["description"] = c.description
+			}
+
+			var names []string
+			for _, tag := range ExtractPostHashtags(models.Post{Body: body}) {
+				if tag.Alias != strings.ToLower(tag.Name) {
+					t.Errorf("unexpected alias %s for tag %s", tag.Alias, tag.Name)
+				}
+				names = append(names, tag.Name)
+			}
+			if !reflect.DeepEqual(names, c.expected) {
+				t.Errorf("expected %v, got %v", c.expected, names)
+			}
+		})
+	}
+}
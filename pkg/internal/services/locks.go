@@ -0,0 +1,82 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"github.com/samber/lo"
+	"github.com/spf13/viper"
+)
+
+// LockPost closes the post for editing, the replies and reactions are also rejected if configured
+// The post locked by moderator cannot be unlocked by its author
+func LockPost(item models.Post, reason string, isModerator bool) (models.Post, error) {
+	if item.LockedAt != nil && item.LockedByModerator && !isModerator {
+		return item, fmt.Errorf("post was locked by moderator")
+	}
+
+	item.LockedAt = lo.ToPtr(time.Now())
+	item.LockReason = reason
+	item.LockedByModerator = isModerator
+	if err := database.C.Model(&item).Updates(map[string]any{
+		"locked_at":           item.LockedAt,
+		"lock_reason":         item.LockReason,
+		"locked_by_moderator": item.LockedByModerator,
+	}).Error; err != nil {
+		return item, err
+	}
+	return item, nil
+}
+
+func UnlockPost(item models.Post, isModerator bool) (models.Post, error) {
+	if item.LockedAt == nil {
+		return item, fmt.Errorf("post was not locked")
+	}
+	if item.LockedByModerator && !isModerator {
+		return item, fmt.Errorf("post was locked by moderator")
+	}
+
+	item.LockedAt = nil
+	item.LockReason = ""
+	item.LockedByModerator = false
+	if err := database.C.Model(&item).Updates(map[string]any{
+		"locked_at":           nil,
+		"lock_reason":         "",
+		"locked_by_moderator": false,
+	}).Error; err != nil {
+		return item, err
+	}
+	return item, nil
+}
+
+// IsPostInteractionLocked reports whether the post was closed by its author or moderator
+func IsPostInteractionLocked(item models.Post) bool {
+	return item.LockedAt != nil
+}
+
+func EnsurePostRepliable(id uint) error {
+	if !viper.GetBool("posts.locked_reject_replies") {
+		return nil
+	}
+
+	var item models.Post
+	if err := database.C.Where("id = ?", id).Select("id", "locked_at", "lock_reason").First(&item).Error; err != nil {
+		return fmt.Errorf("unable to find post to reply: %v", err)
+	}
+	if IsPostInteractionLocked(item) {
+		return fmt.Errorf("post was locked: %s", item.LockReason)
+	}
+	return nil
+}
+
+func EnsurePostReactable(item models.Post) error {
+	if !viper.GetBool("posts.locked_reject_reactions") {
+		return nil
+	}
+	if IsPostInteractionLocked(item) {
+		return fmt.Errorf("post was locked: %s", item.LockReason)
+	}
+	return nil
+}
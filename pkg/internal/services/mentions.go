@@ -0,0 +1,157 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/gap"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"git.solsynth.dev/hypernet/nexus/pkg/proto"
+	"git.solsynth.dev/hypernet/passport/pkg/authkit"
+	authm "git.solsynth.dev/hypernet/passport/pkg/authkit/models"
+	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+// The mentions more than this limit in a single post will be ignored
+const maxPostMentions = 16
+
+// The name of the remote publishers contains the host part, like @someone@example.com
+var mentionRegex = regexp.MustCompile(`(?:^|[^A-Za-z0-9_@.])@([A-Za-z0-9_]+(?:[.-][A-Za-z0-9_]+)*(?:@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)+)?)`)
+
+// ExtractPostMentions returns the publisher names mentioned in the post content
+func ExtractPostMentions(item models.Post) []string {
+	var names []string
+	for _, key := range []string{"content", "description"} {
+		text, ok := item.Body[key].(string)
+		if !ok {
+			continue
+		}
+		for _, match := range mentionRegex.FindAllStringSubmatch(text, -1) {
+			names = append(names, strings.ToLower(match[1]))
+		}
+	}
+	names = lo.Uniq(names)
+	if len(names) > maxPostMentions {
+		names = names[:maxPostMentions]
+	}
+	return names
+}
+
+// EnsurePostMentions resolves the mentioned names into publishers
+// The names which do not belong to any publisher will be ignored
+func EnsurePostMentions(item models.Post) (models.Post, error) {
+	names := ExtractPostMentions(item)
+	if len(names) == 0 {
+		item.Mentions = nil
+		return item, nil
+	}
+
+	var publishers []models.Publisher
+	if err := database.C.Where("LOWER(name) IN ?", names).Find(&publishers).Error; err != nil {
+		return item, fmt.Errorf("unable to resolve mentions: %v", err)
+	}
+	item.Mentions = publishers
+
+	return item, nil
+}
+
+// FilterPostWithMentioned lists the posts mentioned any publisher of the account
+func FilterPostWithMentioned(tx *gorm.DB, uid uint) *gorm.DB {
+	return tx.Where(
+		"id IN (?)",
+		database.C.Table("post_mentions").
+			Select("post_id").
+			Where("publisher_id IN (?)", database.C.Model(&models.Publisher{}).Select("id").Where("account_id = ?", uid)),
+	)
+}
+
+// NotifyMentions tells the mentioned publishers they got mentioned
+// The publishers in the skip list will not be notified, it is used to prevent notifying again after edit
+// The accounts that cannot see the post or have a block relationship with the author will be skipped too
+func NotifyMentions(item models.Post, user models.Publisher, skip ...uint) error {
+	content, ok := item.Body["content"].(string)
+	if !ok {
+		content = "Mentioned you in a post"
+	} else {
+		content = TruncatePostContentShort(content)
+	}
+
+	notified := make(map[uint]bool)
+	for _, target := range item.Mentions {
+		if lo.Contains(skip, target.ID) || target.AccountID == nil {
+			continue
+		}
+		if user.AccountID != nil && *target.AccountID == *user.AccountID {
+			continue
+		}
+		if notified[*target.AccountID] {
+			continue
+		}
+		if !canAccountReceiveMention(item, user, *target.AccountID) {
+			continue
+		}
+		notified[*target.AccountID] = true
+
+		log.Debug().Uint("user", *target.AccountID).Uint("post", item.ID).Msg("Notifying the publisher they got mentioned...")
+		err := NotifyPosterAccount(
+			target,
+			item,
+			"Mentioned in a post",
+			fmt.Sprintf("%s (%s) mentioned you: %s", user.Nick, user.Name, content),
+			"interactive.mention",
+			fmt.Sprintf("%s mentioned %s in post #%d", user.Nick, target.Nick, item.ID),
+		)
+		if err != nil {
+			log.Error().Err(err).Msg("An error occurred when notifying user...")
+		}
+	}
+
+	return nil
+}
+
+func canAccountReceiveMention(item models.Post, author models.Publisher, account uint) bool {
+	switch item.Visibility {
+	case models.PostVisibilityNone:
+		return false
+	case models.PostVisibilitySelected:
+		if !lo.Contains(item.VisibleUsers, account) {
+			return false
+		}
+	case models.PostVisibilityFiltered:
+		if lo.Contains(item.InvisibleUsers, account) {
+			return false
+		}
+	case models.PostVisibilityFriends:
+		if author.AccountID == nil {
+			return false
+		}
+		friends, _ := authkit.ListRelative(gap.Nx, *author.AccountID, int32(authm.RelationshipFriend), true)
+		if !lo.ContainsBy(friends, func(item *proto.UserInfo) bool {
+			return uint(item.GetId()) == account
+		}) {
+			return false
+		}
+	}
+
+	if item.RealmID != nil {
+		if _, err := authkit.GetRealmMember(gap.Nx, *item.RealmID, account); err != nil {
+			return false
+		}
+	}
+
+	if author.AccountID != nil {
+		blocked, _ := authkit.ListRelative(gap.Nx, account, int32(authm.RelationshipBlocked), false)
+		gotBlocked, _ := authkit.ListRelative(gap.Nx, account, int32(authm.RelationshipBlocked), true)
+		if lo.ContainsBy(append(blocked, gotBlocked...), func(item *proto.UserInfo) bool {
+			return uint(item.GetId()) == *author.AccountID
+		}) {
+			return false
+		}
+	}
+
+	return true
+}
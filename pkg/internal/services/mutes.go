@@ -0,0 +1,262 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/gap"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"git.solsynth.dev/hypernet/nexus/pkg/nex/cachekit"
+	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+const mutedPostTextExpr = "concat_ws(' ', body->>'title', body->>'description', body->>'content')"
+
+// Every regex mute adds a predicate to the feed queries, so the amount and the size of them are limited
+const (
+	maxRegexMutesPerUser = 10
+	maxRegexMuteLength   = 128
+)
+
+func ListMutes(uid uint) ([]models.Mute, error) {
+	var mutes []models.Mute
+	err := database.C.
+		Where("account_id = ? AND (expired_at IS NULL OR expired_at > ?)", uid, time.Now()).
+		Preload("Publisher").
+		Preload("Tag").
+		Preload("Category").
+		Order("created_at DESC").
+		Find(&mutes).Error
+	return mutes, err
+}
+
+// ListActiveMutes returns the mutes of the accounts which are not expired yet
+func ListActiveMutes(uid ...uint) ([]models.Mute, error) {
+	var mutes []models.Mute
+	err := database.C.
+		Where("account_id IN ? AND (expired_at IS NULL OR expired_at > ?)", uid, time.Now()).
+		Find(&mutes).Error
+	return mutes, err
+}
+
+func GetMute(id uint, uid uint) (models.Mute, error) {
+	var mute models.Mute
+	err := database.C.Where("id = ? AND account_id = ?", id, uid).First(&mute).Error
+	return mute, err
+}
+
+func NewMute(mute models.Mute) (models.Mute, error) {
+	if mute.ExpiredAt != nil && mute.ExpiredAt.Before(time.Now()) {
+		return mute, fmt.Errorf("mute cannot expire before now")
+	}
+
+	tx := database.C.Where("account_id = ? AND type = ?", mute.AccountID, mute.Type)
+	switch mute.Type {
+	case models.MuteTypePublisher:
+		if mute.PublisherID == nil {
+			return mute, fmt.Errorf("publisher mute requires a publisher")
+		}
+		tx = tx.Where("publisher_id = ?", *mute.PublisherID)
+	case models.MuteTypeTag:
+		if mute.TagID == nil {
+			return mute, fmt.Errorf("tag mute requires a tag")
+		}
+		tx = tx.Where("tag_id = ?", *mute.TagID)
+	case models.MuteTypeCategory:
+		if mute.CategoryID == nil {
+			return mute, fmt.Errorf("category mute requires a category")
+		}
+		tx = tx.Where("category_id = ?", *mute.CategoryID)
+	case models.MuteTypeKeyword:
+		if mute.Keyword == nil || len(strings.TrimSpace(*mute.Keyword)) == 0 {
+			return mute, fmt.Errorf("keyword mute requires a keyword")
+		}
+		if mute.IsRegex {
+			if utf8.RuneCountInString(*mute.Keyword) > maxRegexMuteLength {
+				return mute, fmt.Errorf("regular expression cannot be longer than %d characters", maxRegexMuteLength)
+			}
+			// The regular expressions are always run by the database, so only its syntax is accepted
+			var matched bool
+			if err := database.C.Raw("SELECT '' ~* ?", *mute.Keyword).Scan(&matched).Error; err != nil {
+				return mute, fmt.Errorf("invalid regular expression: %v", err)
+			}
+			var count int64
+			if err := database.C.Model(&models.Mute{}).
+				Where("account_id = ? AND type = ? AND is_regex = ?", mute.AccountID, models.MuteTypeKeyword, true).
+				Where("expired_at IS NULL OR expired_at > ?", time.Now()).
+				Count(&count).Error; err != nil {
+				return mute, err
+			} else if count >= maxRegexMutesPerUser {
+				return mute, fmt.Errorf("cannot have more than %d regular expression mutes", maxRegexMutesPerUser)
+			}
+		}
+		tx = tx.Where("keyword = ? AND is_regex = ?", *mute.Keyword, mute.IsRegex)
+	default:
+		return mute, fmt.Errorf("unknown mute type %s", mute.Type)
+	}
+
+	var count int64
+	if err := tx.Model(&models.Mute{}).
+		Where("expired_at IS NULL OR expired_at > ?", time.Now()).
+		Count(&count).Error; err != nil {
+		return mute, err
+	} else if count > 0 {
+		return mute, fmt.Errorf("already muted")
+	}
+
+	if err := database.C.Create(&mute).Error; err != nil {
+		return mute, err
+	}
+	InvalidatePostUserFilter(mute.AccountID)
+
+	return mute, nil
+}
+
+func DeleteMute(mute models.Mute) error {
+	if err := database.C.Delete(&mute).Error; err != nil {
+		return err
+	}
+	InvalidatePostUserFilter(mute.AccountID)
+	return nil
+}
+
+// InvalidatePostUserFilter drops the cached state of FilterPostWithUserContext
+func InvalidatePostUserFilter(uid uint) {
+	if err := cachekit.Delete(gap.Ca, fmt.Sprintf("post-user-filter#%d", uid)); err != nil {
+		log.Warn().Err(err).Uint("user", uid).Msg("An error occurred when invalidating post user filter...")
+	}
+}
+
+// GetMutesCacheTTL shortens the cache ttl when any mute will be expired before the cache does
+func GetMutesCacheTTL(mutes []models.Mute, ttl time.Duration) time.Duration {
+	for _, mute := range mutes {
+		if mute.ExpiredAt == nil {
+			continue
+		}
+		if remain := time.Until(*mute.ExpiredAt); remain > 0 && remain < ttl {
+			ttl = remain
+		}
+	}
+	return ttl
+}
+
+func FilterPostWithMutes(tx *gorm.DB, mutes []models.Mute) *gorm.DB {
+	mutes = lo.Filter(mutes, func(item models.Mute, index int) bool {
+		return item.ExpiredAt == nil || item.ExpiredAt.After(time.Now())
+	})
+	if len(mutes) == 0 {
+		return tx
+	}
+
+	var publishers, tags, categories []uint
+	var regexCount int
+	for _, mute := range mutes {
+		switch mute.Type {
+		case models.MuteTypePublisher:
+			publishers = append(publishers, *mute.PublisherID)
+		case models.MuteTypeTag:
+			tags = append(tags, *mute.TagID)
+		case models.MuteTypeCategory:
+			categories = append(categories, *mute.CategoryID)
+		case models.MuteTypeKeyword:
+			if mute.IsRegex {
+				// The mutes created before the limit was added are still capped here
+				if regexCount++; regexCount > maxRegexMutesPerUser {
+					continue
+				}
+				tx = tx.Where("NOT ("+mutedPostTextExpr+" ~* ?)", *mute.Keyword)
+			} else {
+				tx = tx.Where("NOT ("+mutedPostTextExpr+" ILIKE ?)", "%"+escapeLikePattern(*mute.Keyword)+"%")
+			}
+		}
+	}
+
+	if len(publishers) > 0 {
+		tx = tx.Where("publisher_id NOT IN ?", publishers)
+	}
+	if len(tags) > 0 {
+		tx = tx.Where("id NOT IN (?)", database.C.Table("post_tags").Select("post_id").Where("tag_id IN ?", tags))
+	}
+	if len(categories) > 0 {
+		tx = tx.Where("id NOT IN (?)", database.C.Table("post_categories").Select("post_id").Where("category_id IN ?", categories))
+	}
+
+	return tx
+}
+
+// IsPostMuted checks the post with the mutes in memory
+// The post needs to have the tags and categories loaded
+// The regular expressions are matched by the database, the same as FilterPostWithMutes does
+func IsPostMuted(item models.Post, mutes []models.Mute) bool {
+	text := strings.Join(lo.FilterMap([]string{"title", "description", "content"}, func(key string, index int) (string, bool) {
+		val, ok := item.Body[key].(string)
+		return val, ok
+	}), " ")
+
+	var patterns []string
+	muted := lo.SomeBy(mutes, func(mute models.Mute) bool {
+		if mute.ExpiredAt != nil && mute.ExpiredAt.Before(time.Now()) {
+			return false
+		}
+		switch mute.Type {
+		case models.MuteTypePublisher:
+			return mute.PublisherID != nil && *mute.PublisherID == item.PublisherID
+		case models.MuteTypeTag:
+			return mute.TagID != nil && lo.SomeBy(item.Tags, func(tag models.Tag) bool {
+				return tag.ID == *mute.TagID
+			})
+		case models.MuteTypeCategory:
+			return mute.CategoryID != nil && lo.SomeBy(item.Categories, func(category models.Category) bool {
+				return category.ID == *mute.CategoryID
+			})
+		case models.MuteTypeKeyword:
+			if mute.Keyword == nil {
+				return false
+			}
+			if mute.IsRegex {
+				patterns = append(patterns, *mute.Keyword)
+				return false
+			}
+			return strings.Contains(strings.ToLower(text), strings.ToLower(*mute.Keyword))
+		}
+		return false
+	})
+	if muted || len(patterns) == 0 {
+		return muted
+	}
+
+	if err := database.C.
+		Raw("SELECT EXISTS (SELECT 1 FROM unnest(ARRAY[?]::text[]) AS pattern WHERE ? ~* pattern)", patterns, text).
+		Scan(&muted).Error; err != nil {
+		log.Warn().Err(err).Uint("post", item.ID).Msg("An error occurred when matching regex mutes...")
+		return false
+	}
+	return muted
+}
+
+// FilterMutedAccounts removes the accounts which muted the post from the notification list
+func FilterMutedAccounts(userIDs []uint64, item models.Post) []uint64 {
+	if len(userIDs) == 0 {
+		return userIDs
+	}
+
+	mutes, err := ListActiveMutes(lo.Map(userIDs, func(item uint64, index int) uint {
+		return uint(item)
+	})...)
+	if err != nil {
+		log.Error().Err(err).Msg("An error occurred when getting mutes of accounts...")
+		return userIDs
+	}
+
+	grouped := lo.GroupBy(mutes, func(item models.Mute) uint {
+		return item.AccountID
+	})
+	return lo.Filter(userIDs, func(entry uint64, index int) bool {
+		return !IsPostMuted(item, grouped[uint(entry)])
+	})
+}
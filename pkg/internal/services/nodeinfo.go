@@ -0,0 +1,58 @@
+package services
+
+import (
+	"time"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/gap"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"git.solsynth.dev/hypernet/nexus/pkg/nex/cachekit"
+)
+
+type NodeInfoUsage struct {
+	TotalUsers          int64 `json:"total_users"`
+	ActiveMonthUsers    int64 `json:"active_month_users"`
+	ActiveHalfyearUsers int64 `json:"active_halfyear_users"`
+	LocalPosts          int64 `json:"local_posts"`
+}
+
+// GetNodeInfoUsage counts the local publishers and posts for the instance directories
+// The publishers are counted as users because they are the actors other instances can see
+func GetNodeInfoUsage() (NodeInfoUsage, error) {
+	cacheKey := "nodeinfo-usage"
+	if val, err := cachekit.Get[NodeInfoUsage](gap.Ca, cacheKey); err == nil {
+		return val, nil
+	}
+
+	var usage NodeInfoUsage
+	if err := database.C.Model(&models.Publisher{}).
+		Where("type != ?", models.PublisherTypeFediverse).
+		Count(&usage.TotalUsers).Error; err != nil {
+		return usage, err
+	}
+	if err := database.C.Model(&models.Post{}).
+		Where("fediverse_id IS NULL AND is_draft = ? AND visibility = ?", false, models.PostVisibilityAll).
+		Count(&usage.LocalPosts).Error; err != nil {
+		return usage, err
+	}
+
+	countActive := func(since time.Time) (int64, error) {
+		var count int64
+		err := database.C.Model(&models.Post{}).
+			Where("fediverse_id IS NULL AND is_draft = ? AND published_at > ?", false, since).
+			Distinct("publisher_id").
+			Count(&count).Error
+		return count, err
+	}
+	var err error
+	if usage.ActiveMonthUsers, err = countActive(time.Now().AddDate(0, -1, 0)); err != nil {
+		return usage, err
+	}
+	if usage.ActiveHalfyearUsers, err = countActive(time.Now().AddDate(0, -6, 0)); err != nil {
+		return usage, err
+	}
+
+	cachekit.Set[NodeInfoUsage](gap.Ca, cacheKey, usage, 30*time.Minute)
+
+	return usage, nil
+}
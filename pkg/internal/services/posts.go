@@ -49,9 +49,12 @@ func FilterPostWithUserContext(c *fiber.Ctx, tx *gorm.DB, user *authm.Account) *
 		InvisibleList []uint `json:"invisible"`
 		FollowList    []uint `json:"follow"`
 		RealmList     []uint `json:"realm"`
+
+		Mutes []models.Mute `json:"mutes"`
 	}
 
 	var self, allowlist, invisibleList, followList, realmList []uint
+	var mutes []models.Mute
 
 	statusCacheKey := fmt.Sprintf("post-user-filter#%d", user.ID)
 	state, err := cachekit.Get[userContextState](gap.Ca, statusCacheKey)
@@ -61,6 +64,7 @@ func FilterPostWithUserContext(c *fiber.Ctx, tx *gorm.DB, user *authm.Account) *
 		followList = state.FollowList
 		realmList = state.RealmList
 		self = state.Self
+		mutes = state.Mutes
 	} else {
 		// Get itself
 		{
@@ -150,6 +154,12 @@ func FilterPostWithUserContext(c *fiber.Ctx, tx *gorm.DB, user *authm.Account) *
 			return item.ID
 		})
 
+		// Getting the mutes
+		mutes, err = ListActiveMutes(user.ID)
+		if err != nil {
+			log.Error().Err(err).Msg("An error occurred when getting mutes...")
+		}
+
 		cachekit.Set(
 			gap.Ca,
 			statusCacheKey,
@@ -159,8 +169,9 @@ func FilterPostWithUserContext(c *fiber.Ctx, tx *gorm.DB, user *authm.Account) *
 				RealmList:     realmList,
 				FollowList:    followList,
 				Self:          self,
+				Mutes:         mutes,
 			},
-			5*time.Minute,
+			GetMutesCacheTTL(mutes, 5*time.Minute),
 			fmt.Sprintf("user#%d", user.ID),
 		)
 	}
@@ -210,6 +221,7 @@ func FilterPostWithUserContext(c *fiber.Ctx, tx *gorm.DB, user *authm.Account) *
 	if len(invisibleList) > 0 {
 		tx = tx.Where("publisher_id NOT IN ?", invisibleList)
 	}
+	tx = FilterPostWithMutes(tx, mutes)
 	if len(c.Query("realm")) == 0 {
 		if len(realmList) > 0 {
 			tx = tx.Where("realm_id IN ? OR realm_id IS NULL", realmList)
@@ -316,28 +328,11 @@ func FilterPostDraftWithAuthor(tx *gorm.DB, uid uint) *gorm.DB {
 	return tx.Where("(is_draft = ? OR is_draft IS NULL) OR publisher_id IN ?", false, idSet)
 }
 
-func FilterPostWithFuzzySearch(tx *gorm.DB, probe string) *gorm.DB {
-	if len(probe) == 0 {
-		return tx
-	}
-
-	probe = "%" + probe + "%"
-	return tx.
-		Where(
-			"(? AND body->>'content' ILIKE ? OR ? AND body->>'title' ILIKE ? OR ? AND body->>'description' ILIKE ?)",
-			gorm.Expr("body ? 'content'"),
-			probe,
-			gorm.Expr("body ? 'title'"),
-			probe,
-			gorm.Expr("body ? 'description'"),
-			probe,
-		)
-}
-
 func PreloadGeneral(tx *gorm.DB) *gorm.DB {
 	return tx.
 		Preload("Tags").
 		Preload("Categories").
+		Preload("Mentions").
 		Preload("Publisher").
 		Preload("Poll")
 }
@@ -410,6 +405,7 @@ func ListPost(tx *gorm.DB, take int, offset int, order any, user *uint, noReact
 
 	tx = tx.Preload("Tags").
 		Preload("Categories").
+		Preload("Mentions").
 		Preload("Publisher")
 
 	// Fetch posts
@@ -492,12 +488,17 @@ func EnsurePostCategoriesAndTags(item models.Post) (models.Post, error) {
 			return item, err
 		}
 	}
+	// Merge the hashtags in the content with the tags provided explicitly
+	item.Tags = append(item.Tags, ExtractPostHashtags(item)...)
 	for idx, tag := range item.Tags {
 		item.Tags[idx], err = GetTagOrCreate(tag.Alias, tag.Name)
 		if err != nil {
 			return item, err
 		}
 	}
+	item.Tags = lo.UniqBy(item.Tags, func(item models.Tag) uint {
+		return item.ID
+	})
 	return item, nil
 }
 
@@ -557,6 +558,9 @@ func NotifySubscribers(item models.Post, user models.Publisher) error {
 }
 
 func NewPost(user models.Publisher, item models.Post) (models.Post, error) {
+	if user.SuspendedAt != nil {
+		return item, fmt.Errorf("publisher was suspended: %s", user.SuspendReason)
+	}
 	if item.Alias != nil && len(*item.Alias) == 0 {
 		item.Alias = nil
 	}
@@ -581,10 +585,23 @@ func NewPost(user models.Publisher, item models.Post) (models.Post, error) {
 	start := time.Now()
 
 	log.Debug().Any("tags", item.Tags).Any("categories", item.Categories).Msg("Preparing categories and tags...")
+	if item.ReplyID != nil {
+		if err := EnsurePostRepliable(*item.ReplyID); err != nil {
+			return item, err
+		}
+	}
+
 	item, err := EnsurePostCategoriesAndTags(item)
 	if err != nil {
 		return item, err
 	}
+	item, err = EnsurePostMentions(item)
+	if err != nil {
+		return item, err
+	}
+
+	// The post publish in the future will be handled by the scheduler
+	item.IsScheduled = !item.IsDraft && item.PublishedAt != nil && item.PublishedAt.After(time.Now())
 
 	log.Debug().Msg("Saving post record into database...")
 	if err := database.C.Save(&item).Error; err != nil {
@@ -597,23 +614,29 @@ func NewPost(user models.Publisher, item models.Post) (models.Post, error) {
 		log.Error().Err(err).Msg("An error occurred when updating post attachment meta...")
 	}
 
-	// Notify the original poster its post has been replied
-	if item.ReplyID != nil && !item.IsDraft {
-		go NotifyReplying(item, user)
-	}
-	// Notify the subscriptions
-	if item.ReplyID == nil && !item.IsDraft {
-		go NotifySubscribers(item, user)
+	if !item.IsDraft && !item.IsScheduled {
+		notifyPostPublished(item, user)
 	}
+	// Tell the followers on other instances
+	go FederatePost(item, nil)
 
 	log.Debug().Dur("elapsed", time.Since(start)).Msg("The post is posted.")
 	return item, nil
 }
 
-func EditPost(item models.Post, og models.Post) (models.Post, error) {
+// EditPost saves the changes of the post, the editor is the account who made the changes
+// The non-draft edits will be recorded as the revisions of the post
+func EditPost(item models.Post, og models.Post, editor uint) (models.Post, error) {
 	if _, ok := item.Body["content_truncated"]; ok {
 		return item, fmt.Errorf("prevented from editing post with truncated content")
 	}
+	if item.Publisher.SuspendedAt != nil {
+		return item, fmt.Errorf("publisher was suspended: %s", item.Publisher.SuspendReason)
+	}
+	// The posts from other instances are read-only, they are updated by the activities from their origin
+	if og.FediverseID != nil {
+		return item, fmt.Errorf("post was imported from fediverse")
+	}
 
 	if !item.IsDraft && item.PublishedAt == nil {
 		item.PublishedAt = lo.ToPtr(time.Now())
@@ -639,9 +662,35 @@ func EditPost(item models.Post, og models.Post) (models.Post, error) {
 	if err != nil {
 		return item, err
 	}
+	item, err = EnsurePostMentions(item)
+	if err != nil {
+		return item, err
+	}
+
+	// The archived post is restored when its PublishedUntil was extended
+	if item.ExpiredAt != nil && (item.PublishedUntil == nil || item.PublishedUntil.After(time.Now())) {
+		item.ExpiredAt = nil
+	}
+
+	// The published posts moved into the future will be federated again, but their notifications were already sent
+	isPending := og.IsDraft || (og.IsScheduled && !og.IsRescheduled)
+	item.IsScheduled = !item.IsDraft && item.PublishedAt != nil && item.PublishedAt.After(time.Now())
+	item.IsRescheduled = item.IsScheduled && !isPending
+
+	isRevision := !og.IsDraft && !item.IsDraft
+	if isRevision {
+		// Load the original tags and categories before they are replaced for the revision
+		_ = database.C.Model(&og).Association("Categories").Find(&og.Categories)
+		_ = database.C.Model(&og).Association("Tags").Find(&og.Tags)
+	}
+
+	// Keep the mentioned publishers before the edit to avoid notifying them again
+	var ogMentions []models.Publisher
+	_ = database.C.Model(&og).Association("Mentions").Find(&ogMentions)
 
 	_ = database.C.Model(&item).Association("Categories").Replace(item.Categories)
 	_ = database.C.Model(&item).Association("Tags").Replace(item.Tags)
+	_ = database.C.Model(&item).Association("Mentions").Replace(item.Mentions)
 
 	pub := item.Publisher
 	err = database.C.Save(&item).Error
@@ -653,21 +702,41 @@ func EditPost(item models.Post, og models.Post) (models.Post, error) {
 			log.Error().Err(err).Msg("An error occurred when updating post attachment meta...")
 		}
 
-		if og.IsDraft && !item.IsDraft {
-			// Notify the original poster its post has been replied
-			if item.ReplyID != nil {
-				go NotifyReplying(item, item.Publisher)
-			}
-			// Notify the subscriptions
-			if item.ReplyID == nil {
-				go NotifySubscribers(item, item.Publisher)
+		if isPending && !item.IsDraft && !item.IsScheduled {
+			notifyPostPublished(item, item.Publisher)
+		} else if !isPending && !item.IsDraft {
+			go NotifyMentions(item, item.Publisher, lo.Map(ogMentions, func(item models.Publisher, index int) uint {
+				return item.ID
+			})...)
+		}
+
+		if isRevision {
+			if err := CreatePostRevision(item, og, editor); err != nil {
+				log.Error().Err(err).Uint("post", item.ID).Msg("An error occurred when creating post revision...")
 			}
 		}
+
+		go FederatePost(item, &og)
 	}
 
 	return item, err
 }
 
+func notifyPostPublished(item models.Post, user models.Publisher) {
+	// Notify the original poster its post has been replied
+	if item.ReplyID != nil {
+		go NotifyReplying(item, user)
+	}
+	// Notify the subscriptions
+	if item.ReplyID == nil {
+		go NotifySubscribers(item, user)
+	}
+	// Notify the mentioned publishers
+	if len(item.Mentions) > 0 {
+		go NotifyMentions(item, user)
+	}
+}
+
 func UpdatePostAttachmentMeta(item models.Post, old ...models.Post) error {
 	log.Debug().Any("attachments", item.Body["attachments"]).Msg("Updating post attachments meta...")
 
@@ -754,6 +823,8 @@ func DeletePost(item models.Post) error {
 		return err
 	}
 
+	go FederatePost(item, nil, true)
+
 	// Cleaning up related attachments
 	var body models.PostStoryBody
 	{
@@ -812,9 +883,13 @@ func ReactPost(user authm.Account, reaction models.Reaction) (bool, models.React
 		First(&op).Error; err != nil {
 		return true, reaction, err
 	}
-
 	if err := database.C.Where(reaction).First(&reaction).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Only the new reactions are rejected, the existing ones still can be taken back
+			if err := EnsurePostReactable(op); err != nil {
+				return true, reaction, err
+			}
+
 			if op.Publisher.AccountID != nil && *op.Publisher.AccountID != user.ID {
 				err = NotifyPosterAccount(
 					op.Publisher,
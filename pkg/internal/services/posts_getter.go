@@ -49,6 +49,14 @@ func UniversalPostFilter(c *fiber.Ctx, tx *gorm.DB, cfg ...UniversalPostFilterCo
 		tx = FilterPostReply(tx)
 	}
 
+	if c.QueryBool("bookmarked") {
+		user, authenticated := c.Locals("user").(authm.Account)
+		if !authenticated {
+			return tx, fiber.NewError(fiber.StatusUnauthorized, "you must sign in to filter your bookmarks")
+		}
+		tx = FilterPostWithBookmarked(tx, user.ID)
+	}
+
 	if len(c.Query("author")) > 0 {
 		var author models.Publisher
 		if err := database.C.Where("name = ?", c.Query("author")).First(&author).Error; err != nil {
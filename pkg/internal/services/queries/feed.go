@@ -35,6 +35,11 @@ func GetFeed(c *fiber.Ctx, limit int, user *uint, cursor *time.Time) ([]FeedEntr
 	limitF := float64(limit)
 	interCount := int(math.Ceil(limitF * 0.7))
 	readerCount := int(math.Ceil(limitF * 0.3))
+	fediverseCount := 0
+	if len(services.GetFediverseFriends()) > 0 {
+		fediverseCount = int(math.Ceil(limitF * 0.2))
+		interCount -= fediverseCount
+	}
 
 	// Internal posts
 	interTx, err := services.UniversalPostFilter(c, database.C)
@@ -44,12 +49,30 @@ func GetFeed(c *fiber.Ctx, limit int, user *uint, cursor *time.Time) ([]FeedEntr
 	if cursor != nil {
 		interTx = interTx.Where("published_at < ?", *cursor)
 	}
+	// The reposts from the fediverse are announcing the local posts, they belong to here
+	interTx = interTx.Where("fediverse_id IS NULL OR repost_id IS NOT NULL")
 	posts, err := ListPostForFeed(interTx, interCount, user, c.Get("X-API-Version", "1"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to load interactive posts: %v", err)
 	}
 	feed = append(feed, posts...)
 
+	// Posts pulled from the fediverse friends
+	if fediverseCount > 0 {
+		fediTx, err := services.UniversalPostFilter(c, database.C)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare load fediverse posts: %v", err)
+		}
+		if cursor != nil {
+			fediTx = fediTx.Where("published_at < ?", *cursor)
+		}
+		if fediPosts, err := ListFediversePostForFeed(fediTx, fediverseCount, user); err != nil {
+			log.Error().Err(err).Msg("Failed to load fediverse posts in getting feed...")
+		} else {
+			feed = append(feed, fediPosts...)
+		}
+	}
+
 	sort.Slice(feed, func(i, j int) bool {
 		return feed[i].CreatedAt.After(feed[j].CreatedAt)
 	})
@@ -91,6 +114,24 @@ func ListPostForFeed(tx *gorm.DB, limit int, user *uint, api string) ([]FeedEntr
 	return entries, nil
 }
 
+// The timeline posts pulled from the fediverse are those have no reply or repost
+// Replies and reposts from the fediverse are received by inbox, the reposts are listed with the internal posts
+func ListFediversePostForFeed(tx *gorm.DB, limit int, user *uint) ([]FeedEntry, error) {
+	tx = tx.Where("fediverse_id IS NOT NULL AND reply_id IS NULL AND repost_id IS NULL")
+	posts, err := services.ListPost(tx, limit, 0, "published_at DESC", user)
+	if err != nil {
+		return nil, err
+	}
+	entries := lo.Map(posts, func(post models.Post, _ int) FeedEntry {
+		return FeedEntry{
+			Type:      "fediverse.post",
+			Data:      services.TruncatePostContent(post),
+			CreatedAt: lo.FromPtrOr(post.PublishedAt, post.CreatedAt),
+		}
+	})
+	return entries, nil
+}
+
 func ListReaderPagesForFeed(limit int, cursor *time.Time) ([]FeedEntry, error) {
 	conn, err := gap.Nx.GetClientGrpcConn("re")
 	if err != nil {
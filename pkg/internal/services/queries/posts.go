@@ -163,6 +163,7 @@ func GetPost(tx *gorm.DB, id uint, user *uint) (models.Post, error) {
 	var post models.Post
 	if err := tx.Preload("Tags").
 		Preload("Categories").
+		Preload("Mentions").
 		Preload("Publisher").
 		Preload("Poll").
 		First(&post, id).Error; err != nil {
@@ -185,6 +186,7 @@ func GetPostByAlias(tx *gorm.DB, alias, area string, user *uint) (models.Post, e
 	var post models.Post
 	if err := tx.Preload("Tags").
 		Preload("Categories").
+		Preload("Mentions").
 		Preload("Publisher").
 		Preload("Poll").
 		Where("alias = ?", alias).
@@ -219,6 +221,7 @@ func ListPost(tx *gorm.DB, take int, offset int, order any, user *uint) ([]model
 
 	tx = tx.Preload("Tags").
 		Preload("Categories").
+		Preload("Mentions").
 		Preload("Publisher").
 		Preload("Poll")
 
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"github.com/goccy/go-json"
+	"github.com/samber/lo"
+)
+
+func newPostRevision(item models.Post, editor *uint) models.PostRevision {
+	return models.PostRevision{
+		Body: item.Body,
+		Tags: lo.Map(item.Tags, func(item models.Tag, index int) string {
+			return item.Alias
+		}),
+		Categories: lo.Map(item.Categories, func(item models.Category, index int) string {
+			return item.Alias
+		}),
+		Visibility: item.Visibility,
+		EditorID:   editor,
+		PostID:     item.ID,
+	}
+}
+
+// CreatePostRevision stores the snapshot of the post after it was edited
+// The original version will be stored too if the post has no revision yet
+// Nothing will be stored if the post has not changed since the last revision
+func CreatePostRevision(item models.Post, og models.Post, editor uint) error {
+	var last models.PostRevision
+	if err := database.C.Where("post_id = ?", item.ID).Order("created_at DESC").Limit(1).Find(&last).Error; err != nil {
+		return err
+	}
+
+	if last.ID == 0 {
+		original := newPostRevision(og, og.Publisher.AccountID)
+		original.CreatedAt = lo.FromPtrOr(og.EditedAt, lo.FromPtrOr(og.PublishedAt, og.CreatedAt))
+		if err := database.C.Create(&original).Error; err != nil {
+			return err
+		}
+		last = original
+	}
+
+	revision := newPostRevision(item, &editor)
+	if isSamePostRevision(last, revision) {
+		return nil
+	}
+
+	return database.C.Create(&revision).Error
+}
+
+func isSamePostRevision(a, b models.PostRevision) bool {
+	return a.Visibility == b.Visibility &&
+		reflect.DeepEqual(normalizePostRevisionBody(a.Body), normalizePostRevisionBody(b.Body)) &&
+		isSameAliases(a.Tags, b.Tags) &&
+		isSameAliases(a.Categories, b.Categories)
+}
+
+func isSameAliases(a, b []string) bool {
+	left, right := lo.Difference(a, b)
+	return len(left) == 0 && len(right) == 0
+}
+
+// The body was loaded from database and the new one comes from a struct
+// Convert them into the same form before comparing
+func normalizePostRevisionBody(body map[string]any) map[string]any {
+	var out map[string]any
+	raw, _ := json.Marshal(body)
+	_ = json.Unmarshal(raw, &out)
+	return out
+}
+
+func ListPostRevisions(item models.Post, take, offset int) ([]models.PostRevision, int64, error) {
+	var count int64
+	if err := database.C.Model(&models.PostRevision{}).Where("post_id = ?", item.ID).Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var revisions []models.PostRevision
+	if err := database.C.Where("post_id = ?", item.ID).
+		Order("created_at DESC").
+		Limit(take).Offset(offset).
+		Find(&revisions).Error; err != nil {
+		return nil, count, err
+	}
+
+	return revisions, count, nil
+}
+
+func GetPostRevision(item models.Post, id uint) (models.PostRevision, error) {
+	var revision models.PostRevision
+	err := database.C.Where("post_id = ? AND id = ?", item.ID, id).First(&revision).Error
+	return revision, err
+}
+
+// RestorePostRevision applies the revision to the post
+// It will go through EditPost, so the restoring itself will be a new revision
+func RestorePostRevision(item models.Post, revision models.PostRevision, editor uint) (models.Post, error) {
+	if item.LockedAt != nil {
+		return item, fmt.Errorf("post was locked")
+	}
+
+	og := item
+	item.Body = revision.Body
+	item.Tags = lo.Map(revision.Tags, func(alias string, index int) models.Tag {
+		return models.Tag{Alias: alias, Name: alias}
+	})
+	item.Categories = lo.Map(revision.Categories, func(alias string, index int) models.Category {
+		return models.Category{Alias: alias}
+	})
+	item.Visibility = revision.Visibility
+	item.EditedAt = lo.ToPtr(time.Now())
+	if content, ok := item.Body["content"].(string); ok {
+		item.Language = DetectLanguage(content)
+	}
+
+	return EditPost(item, og, editor)
+}
+
+type PostRevisionDiffLine struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type PostRevisionDiff struct {
+	From              models.PostRevision               `json:"from"`
+	To                models.PostRevision               `json:"to"`
+	Fields            map[string][]PostRevisionDiffLine `json:"fields"`
+	TagsAdded         []string                          `json:"tags_added"`
+	TagsRemoved       []string                          `json:"tags_removed"`
+	CategoriesAdded   []string                          `json:"categories_added"`
+	CategoriesRemoved []string                          `json:"categories_removed"`
+	VisibilityChanged bool                              `json:"visibility_changed"`
+}
+
+// DiffPostRevision compares two revisions
+// The text fields of the body will be compared line by line, others are compared as a whole
+func DiffPostRevision(from, to models.PostRevision) PostRevisionDiff {
+	diff := PostRevisionDiff{
+		From:              from,
+		To:                to,
+		Fields:            make(map[string][]PostRevisionDiffLine),
+		VisibilityChanged: from.Visibility != to.Visibility,
+	}
+	diff.TagsRemoved, diff.TagsAdded = lo.Difference(from.Tags, to.Tags)
+	diff.CategoriesRemoved, diff.CategoriesAdded = lo.Difference(from.Categories, to.Categories)
+
+	fromBody, toBody := normalizePostRevisionBody(from.Body), normalizePostRevisionBody(to.Body)
+	keys := lo.Uniq(append(lo.Keys(fromBody), lo.Keys(toBody)...))
+	for _, key := range keys {
+		if reflect.DeepEqual(fromBody[key], toBody[key]) {
+			continue
+		}
+		diff.Fields[key] = diffLines(stringifyRevisionField(fromBody[key]), stringifyRevisionField(toBody[key]))
+	}
+
+	return diff
+}
+
+func stringifyRevisionField(value any) string {
+	switch val := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	default:
+		raw, _ := json.Marshal(val)
+		return string(raw)
+	}
+}
+
+// maxRevisionDiffCells caps the size of the LCS table, the changed part larger than it is shown as replaced as a whole
+const maxRevisionDiffCells = 1 << 20
+
+// diffLines produces the line based diff by using the longest common subsequence
+// The common head and tail are skipped before building the table, so the small edits on long posts stay cheap
+func diffLines(a, b string) []PostRevisionDiffLine {
+	var left, right []string
+	if len(a) > 0 {
+		left = strings.Split(a, "\n")
+	}
+	if len(b) > 0 {
+		right = strings.Split(b, "\n")
+	}
+
+	var head, tail []PostRevisionDiffLine
+	for len(left) > 0 && len(right) > 0 && left[0] == right[0] {
+		head = append(head, PostRevisionDiffLine{Type: "equal", Text: left[0]})
+		left, right = left[1:], right[1:]
+	}
+	for len(left) > 0 && len(right) > 0 && left[len(left)-1] == right[len(right)-1] {
+		tail = append([]PostRevisionDiffLine{{Type: "equal", Text: left[len(left)-1]}}, tail...)
+		left, right = left[:len(left)-1], right[:len(right)-1]
+	}
+
+	out := head
+	if (len(left)+1)*(len(right)+1) > maxRevisionDiffCells {
+		for _, line := range left {
+			out = append(out, PostRevisionDiffLine{Type: "delete", Text: line})
+		}
+		for _, line := range right {
+			out = append(out, PostRevisionDiffLine{Type: "insert", Text: line})
+		}
+		return append(out, tail...)
+	}
+
+	width := len(right) + 1
+	lcs := make([]int, (len(left)+1)*width)
+	for i := len(left) - 1; i >= 0; i-- {
+		for j := len(right) - 1; j >= 0; j-- {
+			if left[i] == right[j] {
+				lcs[i*width+j] = lcs[(i+1)*width+j+1] + 1
+			} else {
+				lcs[i*width+j] = max(lcs[(i+1)*width+j], lcs[i*width+j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(left) && j < len(right) {
+		switch {
+		case left[i] == right[j]:
+			out = append(out, PostRevisionDiffLine{Type: "equal", Text: left[i]})
+			i++
+			j++
+		case lcs[(i+1)*width+j] >= lcs[i*width+j+1]:
+			out = append(out, PostRevisionDiffLine{Type: "delete", Text: left[i]})
+			i++
+		default:
+			out = append(out, PostRevisionDiffLine{Type: "insert", Text: right[j]})
+			j++
+		}
+	}
+	for ; i < len(left); i++ {
+		out = append(out, PostRevisionDiffLine{Type: "delete", Text: left[i]})
+	}
+	for ; j < len(right); j++ {
+		out = append(out, PostRevisionDiffLine{Type: "insert", Text: right[j]})
+	}
+
+	return append(out, tail...)
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var scheduledPostLock sync.Mutex
+
+// PublishScheduledPosts runs the notifications and federation of the posts whose PublishedAt arrived
+// Every post will be claimed before processing, so the side effects only happen once
+// The rescheduled posts were published before, they will only be federated again
+func PublishScheduledPosts() {
+	if !scheduledPostLock.TryLock() {
+		return
+	}
+	defer scheduledPostLock.Unlock()
+
+	var posts []models.Post
+	if err := database.C.
+		Where("is_scheduled = ? AND is_draft = ? AND published_at <= ?", true, false, time.Now()).
+		Preload("Tags").
+		Preload("Categories").
+		Preload("Mentions").
+		Preload("Publisher").
+		Find(&posts).Error; err != nil {
+		log.Error().Err(err).Msg("An error occurred when fetching scheduled posts...")
+		return
+	}
+	if len(posts) == 0 {
+		return
+	}
+
+	log.Debug().Int("count", len(posts)).Msg("Publishing scheduled posts...")
+
+	for _, post := range posts {
+		tx := database.C.Model(&models.Post{}).
+			Where("id = ? AND is_scheduled = ?", post.ID, true).
+			Updates(map[string]any{"is_scheduled": false, "is_rescheduled": false})
+		if tx.Error != nil {
+			log.Error().Err(tx.Error).Uint("post", post.ID).Msg("An error occurred when publishing scheduled post...")
+			continue
+		} else if tx.RowsAffected == 0 {
+			continue
+		}
+
+		post.IsScheduled = false
+		if !post.IsRescheduled {
+			notifyPostPublished(post, post.Publisher)
+		}
+		post.IsRescheduled = false
+		go FederatePost(post, nil)
+	}
+}
+
+// FilterPostScheduled lists the posts waiting to be published of the publisher
+func FilterPostScheduled(tx *gorm.DB, publisher models.Publisher) *gorm.DB {
+	return tx.Where("publisher_id = ? AND is_scheduled = ? AND is_draft = ?", publisher.ID, true, false)
+}
+
+// ReschedulePost changes the time to publish a scheduled post
+// The post will be published at the next tick of the scheduler when the time is already passed
+func ReschedulePost(item models.Post, publishedAt time.Time) (models.Post, error) {
+	if !item.IsScheduled {
+		return item, fmt.Errorf("post is not scheduled")
+	}
+	if item.PublishedUntil != nil && !publishedAt.Before(*item.PublishedUntil) {
+		return item, fmt.Errorf("post cannot be published after it expired")
+	}
+
+	item.PublishedAt = &publishedAt
+	err := database.C.Model(&item).Update("published_at", publishedAt).Error
+	return item, err
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostSearchTerms is the parsed search term
+// The excluded terms are kept apart, so they can be applied on every text search config at once
+type PostSearchTerms struct {
+	// Include is the tsquery text of the terms should be matched
+	Include string
+	// Exclude is the tsquery text matching any of the excluded terms
+	Exclude string
+	// Substrings are the included words for the substring matching fallback
+	Substrings []string
+}
+
+// ParsePostSearchQuery turns the search term into the text of PostgreSQL tsquery
+// The supported syntax:
+//   - "some words" matches the phrase
+//   - -word excludes the posts with the word, it always applies no matter the OR around it
+//   - word* matches the words starting with it
+//   - OR between two terms matches either of them, the other terms are joined with AND
+func ParsePostSearchQuery(probe string) (PostSearchTerms, error) {
+	var out PostSearchTerms
+	var terms, excluded []string
+	var operators []string
+	isOr := false
+
+	runes := []rune(strings.TrimSpace(probe))
+	for idx := 0; idx < len(runes); {
+		if unicode.IsSpace(runes[idx]) {
+			idx++
+			continue
+		}
+
+		isExcluded := false
+		if runes[idx] == '-' && idx+1 < len(runes) && !unicode.IsSpace(runes[idx+1]) {
+			isExcluded = true
+			idx++
+		}
+
+		var term, text string
+		if runes[idx] == '"' {
+			end := idx + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end >= len(runes) {
+				return out, fmt.Errorf("unclosed quote in search term")
+			}
+			text = string(runes[idx+1 : end])
+			term = buildSearchPhrase(text, false)
+			idx = end + 1
+		} else {
+			end := idx
+			for end < len(runes) && !unicode.IsSpace(runes[end]) {
+				end++
+			}
+			word := string(runes[idx:end])
+			idx = end
+			if word == "OR" && !isExcluded {
+				if len(terms) > 0 {
+					isOr = true
+				}
+				continue
+			}
+			text = strings.TrimSuffix(word, "*")
+			term = buildSearchPhrase(text, strings.HasSuffix(word, "*"))
+		}
+
+		if len(term) == 0 {
+			continue
+		}
+		if isExcluded {
+			excluded = append(excluded, term)
+			continue
+		}
+		if len(terms) > 0 {
+			operators = append(operators, lo.Ternary(isOr, "|", "&"))
+		}
+		terms = append(terms, term)
+		out.Substrings = append(out.Substrings, text)
+		isOr = false
+	}
+
+	if len(terms) == 0 {
+		return out, fmt.Errorf("search term has no searchable words")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(terms[0])
+	for idx, operator := range operators {
+		sb.WriteString(" " + operator + " " + terms[idx+1])
+	}
+	out.Include = sb.String()
+	out.Exclude = strings.Join(excluded, " | ")
+	return out, nil
+}
+
+// buildSearchPhrase splits the text into words and joins them as a phrase
+// The characters other than letters and numbers are removed to prevent breaking the tsquery syntax
+func buildSearchPhrase(text string, isPrefix bool) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && !unicode.IsMark(r) && r != '_'
+	})
+	if len(words) == 0 {
+		return ""
+	}
+	words = lo.Map(words, func(item string, index int) string {
+		return "'" + item + "'"
+	})
+	if isPrefix {
+		words[len(words)-1] += ":*"
+	}
+	if len(words) == 1 {
+		return words[0]
+	}
+	return "(" + strings.Join(words, " <-> ") + ")"
+}
+
+// PostSearchQuery is the built search term
+// Match is the tsquery of the included terms, used to rank and highlight the results
+// Filter is the condition of the results, it also contains the exclusions and the substring fallback
+type PostSearchQuery struct {
+	Match  clause.Expr
+	Filter clause.Expr
+}
+
+const postSearchTextExpr = "concat_ws(' ', body->>'title', body->>'description', body->>'content')"
+
+// BuildPostSearchQuery builds the tsquery expression of the search term
+// The query is normalized both by the simple config and the config of the language detected from the term,
+// so it can match the posts in the other languages and the stemmed words in the same language
+// The CJK words are not split by the simple config, so the posts containing them are matched as substrings as well
+func BuildPostSearchQuery(probe string) (PostSearchQuery, error) {
+	terms, err := ParsePostSearchQuery(probe)
+	if err != nil {
+		return PostSearchQuery{}, err
+	}
+
+	config := database.GetSearchConfig(DetectLanguage(probe))
+	buildTsQuery := func(text string) clause.Expr {
+		return gorm.Expr(
+			"(to_tsquery(?::regconfig, ?) || to_tsquery(?::regconfig, ?))",
+			database.DefaultSearchConfig, text,
+			config, text,
+		)
+	}
+
+	match := buildTsQuery(terms.Include)
+	filter := gorm.Expr("search_vector @@ ?", match)
+	if lo.SomeBy(terms.Substrings, containsCJK) {
+		conditions := lo.Map(terms.Substrings, func(item string, index int) clause.Expression {
+			return gorm.Expr(postSearchTextExpr+" ILIKE ?", "%"+escapeLikePattern(item)+"%")
+		})
+		filter = gorm.Expr("(? OR (?))", filter, clause.And(conditions...))
+	}
+	if len(terms.Exclude) > 0 {
+		filter = gorm.Expr("(? AND NOT search_vector @@ ?)", filter, buildTsQuery(terms.Exclude))
+	}
+
+	return PostSearchQuery{Match: match, Filter: filter}, nil
+}
+
+func containsCJK(text string) bool {
+	for _, r := range text {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			return true
+		}
+	}
+	return false
+}
+
+func FilterPostWithFullTextSearch(tx *gorm.DB, query PostSearchQuery) *gorm.DB {
+	return tx.Where(query.Filter)
+}
+
+// GetPostSearchOrder ranks the posts by the relevance to the query, and then the newer ones
+func GetPostSearchOrder(query PostSearchQuery) clause.OrderBy {
+	return clause.OrderBy{
+		Expression: clause.Expr{
+			SQL:  "ts_rank(search_vector, ?) DESC, published_at DESC",
+			Vars: []any{query.Match},
+		},
+	}
+}
+
+// The markers of the matched words, they are replaced with the mark tags after the snippet is escaped
+const (
+	postSearchHighlightStart = "\uE000"
+	postSearchHighlightStop  = "\uE001"
+)
+
+// HighlightPostSearch fills the highlighted snippets of the matched words into the posts
+// The snippets are HTML escaped, only the mark tags around the matched words are left
+func HighlightPostSearch(items []models.Post, query PostSearchQuery) ([]models.Post, error) {
+	if len(items) == 0 {
+		return items, nil
+	}
+
+	var highlights []struct {
+		ID        uint
+		Highlight string
+	}
+	if err := database.C.Model(&models.Post{}).
+		Select(
+			"id, ts_headline("+database.GetSearchConfigExpr()+", concat_ws(' ', body->>'title', body->>'description', body->>'content'), ?, ?) AS highlight",
+			query.Match,
+			"MaxFragments=2, MaxWords=24, MinWords=8, StartSel="+postSearchHighlightStart+", StopSel="+postSearchHighlightStop,
+		).
+		Where("id IN ?", lo.Map(items, func(item models.Post, index int) uint {
+			return item.ID
+		})).
+		Scan(&highlights).Error; err != nil {
+		return items, err
+	}
+
+	for _, highlight := range highlights {
+		for idx := range items {
+			if items[idx].ID == highlight.ID {
+				items[idx].Highlight = strings.NewReplacer(
+					postSearchHighlightStart, "<mark>",
+					postSearchHighlightStop, "</mark>",
+				).Replace(html.EscapeString(highlight.Highlight))
+			}
+		}
+	}
+
+	return items, nil
+}
+
+// escapeLikePattern escapes the wildcards, so the text is matched as it is by LIKE
+func escapeLikePattern(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(text)
+}
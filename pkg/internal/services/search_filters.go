@@ -0,0 +1,169 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/database"
+	"git.solsynth.dev/hypernet/interactive/pkg/internal/models"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+// PostSearchFilter is the filters written as operators in the search term, like from:someone
+type PostSearchFilter struct {
+	From       *string
+	Before     *time.Time
+	After      *time.Time
+	HasMedia   bool
+	HasPoll    bool
+	Language   *string
+	MinUpvotes *int
+	Type       *string
+}
+
+var postSearchTypes = []string{
+	models.PostTypeStory,
+	models.PostTypeArticle,
+	models.PostTypeQuestion,
+	models.PostTypeVideo,
+}
+
+// ParsePostSearchFilter takes the operators out of the search term
+// It returns the filter and the rest of the term, the invalid value of the known operators is an error
+// The words in the quoted phrases and the unknown operators like urls are kept in the term
+func ParsePostSearchFilter(probe string) (PostSearchFilter, string, error) {
+	var filter PostSearchFilter
+	var rest []string
+
+	for _, token := range splitSearchTokens(probe) {
+		key, value, ok := strings.Cut(token, ":")
+		if !ok || strings.HasPrefix(token, "\"") {
+			rest = append(rest, token)
+			continue
+		}
+
+		key = strings.ToLower(key)
+		switch key {
+		case "from":
+			value = strings.TrimPrefix(value, "@")
+			if len(value) == 0 {
+				return filter, probe, fmt.Errorf("operator from: requires a publisher name")
+			}
+			filter.From = &value
+		case "before", "after":
+			date, err := parseSearchDate(value)
+			if err != nil {
+				return filter, probe, fmt.Errorf("operator %s: has an invalid date %q, use YYYY-MM-DD or RFC3339", key, value)
+			}
+			if key == "before" {
+				filter.Before = &date
+			} else {
+				filter.After = &date
+			}
+		case "has":
+			switch strings.ToLower(value) {
+			case "media":
+				filter.HasMedia = true
+			case "poll":
+				filter.HasPoll = true
+			default:
+				return filter, probe, fmt.Errorf("operator has: only accepts media or poll, got %q", value)
+			}
+		case "lang":
+			if len(value) == 0 {
+				return filter, probe, fmt.Errorf("operator lang: requires a language")
+			}
+			filter.Language = lo.ToPtr(strings.ToLower(value))
+		case "min_upvotes":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 0 {
+				return filter, probe, fmt.Errorf("operator min_upvotes: requires a non-negative number, got %q", value)
+			}
+			filter.MinUpvotes = &count
+		case "type":
+			value = strings.ToLower(value)
+			if !lo.Contains(postSearchTypes, value) {
+				return filter, probe, fmt.Errorf("operator type: only accepts %s, got %q", strings.Join(postSearchTypes, ", "), value)
+			}
+			filter.Type = &value
+		default:
+			rest = append(rest, token)
+		}
+	}
+
+	return filter, strings.Join(rest, " "), nil
+}
+
+// IsEmpty tells does the filter have any operator
+func (v PostSearchFilter) IsEmpty() bool {
+	return v.From == nil && v.Before == nil && v.After == nil &&
+		!v.HasMedia && !v.HasPoll &&
+		v.Language == nil && v.MinUpvotes == nil && v.Type == nil
+}
+
+func FilterPostWithSearchFilter(tx *gorm.DB, filter PostSearchFilter) (*gorm.DB, error) {
+	if filter.From != nil {
+		var publisher models.Publisher
+		if err := database.C.Where("name = ?", *filter.From).First(&publisher).Error; err != nil {
+			return tx, fmt.Errorf("publisher %s was not found", *filter.From)
+		}
+		tx = tx.Where("publisher_id = ?", publisher.ID)
+	}
+	if filter.Before != nil {
+		tx = tx.Where("published_at < ?", *filter.Before)
+	}
+	if filter.After != nil {
+		tx = tx.Where("published_at > ?", *filter.After)
+	}
+	if filter.HasMedia {
+		tx = tx.Where("((jsonb_typeof(body->'attachments') = 'array' AND jsonb_array_length(body->'attachments') > 0) OR ?)", gorm.Expr("body ? 'video'"))
+	}
+	if filter.HasPoll {
+		tx = tx.Where("poll_id IS NOT NULL")
+	}
+	if filter.Language != nil {
+		tx = tx.Where("lower(language) IN ?", database.GetLanguageAliases(*filter.Language))
+	}
+	if filter.MinUpvotes != nil {
+		tx = tx.Where("total_upvote >= ?", *filter.MinUpvotes)
+	}
+	if filter.Type != nil {
+		tx = FilterPostWithType(tx, *filter.Type)
+	}
+	return tx, nil
+}
+
+// splitSearchTokens splits the term by spaces, but keeps the quoted phrases as a whole
+func splitSearchTokens(probe string) []string {
+	var tokens []string
+	var current strings.Builder
+	isQuoted := false
+	for _, r := range probe {
+		if r == '"' {
+			isQuoted = !isQuoted
+		}
+		if unicode.IsSpace(r) && !isQuoted {
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteRune(r)
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens
+}
+
+func parseSearchDate(value string) (time.Time, error) {
+	if date, err := time.Parse(time.DateOnly, value); err == nil {
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
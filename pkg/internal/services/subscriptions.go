@@ -11,8 +11,10 @@ import (
 	"git.solsynth.dev/hypernet/passport/pkg/authkit"
 	authm "git.solsynth.dev/hypernet/passport/pkg/authkit/models"
 	"git.solsynth.dev/hypernet/pusher/pkg/pushkit"
+	"github.com/rs/zerolog/log"
 	"github.com/samber/lo"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func GetSubscriptionOnUser(user authm.Account, target models.Publisher) (*models.Subscription, error) {
@@ -50,10 +52,10 @@ func GetSubscriptionOnCategory(user authm.Account, target models.Category) (*mod
 
 func SubscribeToUser(user authm.Account, target models.Publisher) (models.Subscription, error) {
 	var subscription models.Subscription
-	if err := database.C.Where("follower_id = ? AND account_id = ?", user.ID, target.ID).First(&subscription).Error; err != nil {
-		if !errors.Is(err, gorm.ErrRecordNotFound) {
-			return subscription, fmt.Errorf("subscription already exists")
-		}
+	if err := database.C.Where("follower_id = ? AND account_id = ?", user.ID, target.ID).First(&subscription).Error; err == nil {
+		return subscription, fmt.Errorf("subscription already exists")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return subscription, fmt.Errorf("unable to check subscription is exists or not: %v", err)
 	}
 
 	subscription = models.Subscription{
@@ -61,16 +63,24 @@ func SubscribeToUser(user authm.Account, target models.Publisher) (models.Subscr
 		AccountID:  &target.ID,
 	}
 
-	err := database.C.Save(&subscription).Error
-	return subscription, err
+	// The unique index stops the concurrent requests, only the one actually inserted counts
+	tx := database.C.Clauses(clause.OnConflict{DoNothing: true}).Create(&subscription)
+	if tx.Error != nil {
+		return subscription, tx.Error
+	} else if tx.RowsAffected == 0 {
+		return subscription, fmt.Errorf("subscription already exists")
+	}
+	modifyPublisherFollowerCount(target, 1)
+
+	return subscription, nil
 }
 
 func SubscribeToTag(user authm.Account, target models.Tag) (models.Subscription, error) {
 	var subscription models.Subscription
-	if err := database.C.Where("follower_id = ? AND tag_id = ?", user.ID, target.ID).First(&subscription).Error; err != nil {
-		if !errors.Is(err, gorm.ErrRecordNotFound) {
-			return subscription, fmt.Errorf("subscription already exists")
-		}
+	if err := database.C.Where("follower_id = ? AND tag_id = ?", user.ID, target.ID).First(&subscription).Error; err == nil {
+		return subscription, fmt.Errorf("subscription already exists")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return subscription, fmt.Errorf("unable to check subscription is exists or not: %v", err)
 	}
 
 	subscription = models.Subscription{
@@ -84,10 +94,10 @@ func SubscribeToTag(user authm.Account, target models.Tag) (models.Subscription,
 
 func SubscribeToCategory(user authm.Account, target models.Category) (models.Subscription, error) {
 	var subscription models.Subscription
-	if err := database.C.Where("follower_id = ? AND category_id = ?", user.ID, target.ID).First(&subscription).Error; err != nil {
-		if !errors.Is(err, gorm.ErrRecordNotFound) {
-			return subscription, fmt.Errorf("subscription already exists")
-		}
+	if err := database.C.Where("follower_id = ? AND category_id = ?", user.ID, target.ID).First(&subscription).Error; err == nil {
+		return subscription, fmt.Errorf("subscription already exists")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return subscription, fmt.Errorf("unable to check subscription is exists or not: %v", err)
 	}
 
 	subscription = models.Subscription{
@@ -108,8 +118,100 @@ func UnsubscribeFromUser(user authm.Account, target models.Publisher) error {
 		return fmt.Errorf("unable to check subscription is exists or not: %v", err)
 	}
 
-	err := database.C.Delete(&subscription).Error
-	return err
+	tx := database.C.Delete(&subscription)
+	if tx.Error != nil {
+		return tx.Error
+	} else if tx.RowsAffected > 0 {
+		modifyPublisherFollowerCount(target, -1)
+	}
+
+	return nil
+}
+
+func modifyPublisherFollowerCount(target models.Publisher, delta int) {
+	if err := database.C.Model(&models.Publisher{}).
+		Where("id = ?", target.ID).
+		Update("total_followers", gorm.Expr("GREATEST(total_followers + ?, 0)", delta)).Error; err != nil {
+		log.Error().Err(err).Uint("publisher", target.ID).Msg("An error occurred when updating follower count...")
+	}
+}
+
+// ListSubscribedPublishers returns the publishers the user subscribed, the latest subscribed comes first
+func ListSubscribedPublishers(user authm.Account, take, offset int) ([]models.Publisher, int64, error) {
+	tx := database.C.Model(&models.Publisher{}).
+		Joins("JOIN subscriptions ON subscriptions.account_id = publishers.id AND subscriptions.deleted_at IS NULL").
+		Where("subscriptions.follower_id = ?", user.ID)
+
+	var count int64
+	if err := tx.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var publishers []models.Publisher
+	err := tx.Order("subscriptions.created_at DESC").Limit(take).Offset(offset).Find(&publishers).Error
+	return publishers, count, err
+}
+
+func ListSubscribedTags(user authm.Account, take, offset int) ([]models.Tag, int64, error) {
+	tx := database.C.Model(&models.Tag{}).
+		Joins("JOIN subscriptions ON subscriptions.tag_id = tags.id AND subscriptions.deleted_at IS NULL").
+		Where("subscriptions.follower_id = ?", user.ID)
+
+	var count int64
+	if err := tx.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var tags []models.Tag
+	err := tx.Order("subscriptions.created_at DESC").Limit(take).Offset(offset).Find(&tags).Error
+	return tags, count, err
+}
+
+func ListSubscribedCategories(user authm.Account, take, offset int) ([]models.Category, int64, error) {
+	tx := database.C.Model(&models.Category{}).
+		Joins("JOIN subscriptions ON subscriptions.category_id = categories.id AND subscriptions.deleted_at IS NULL").
+		Where("subscriptions.follower_id = ?", user.ID)
+
+	var count int64
+	if err := tx.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var categories []models.Category
+	err := tx.Order("subscriptions.created_at DESC").Limit(take).Offset(offset).Find(&categories).Error
+	return categories, count, err
+}
+
+// ListPublisherFollowers returns the accounts subscribed the publisher, the latest subscribed comes first
+func ListPublisherFollowers(publisher models.Publisher, take, offset int) ([]authm.Account, int64, error) {
+	tx := database.C.Model(&models.Subscription{}).Where("account_id = ?", publisher.ID)
+
+	var count int64
+	if err := tx.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var ids []uint
+	if err := tx.Order("created_at DESC").Limit(take).Offset(offset).Pluck("follower_id", &ids).Error; err != nil {
+		return nil, count, err
+	}
+	if len(ids) == 0 {
+		return []authm.Account{}, count, nil
+	}
+
+	accounts, err := authkit.ListUser(gap.Nx, ids)
+	if err != nil {
+		return nil, count, fmt.Errorf("unable to get accounts: %v", err)
+	}
+
+	// Keep the order of subscriptions
+	accountsMap := lo.SliceToMap(accounts, func(item authm.Account) (uint, authm.Account) {
+		return item.ID, item
+	})
+	return lo.FilterMap(ids, func(id uint, index int) (authm.Account, bool) {
+		account, ok := accountsMap[id]
+		return account, ok
+	}), count, nil
 }
 
 func UnsubscribeFromTag(user authm.Account, target models.Tag) error {
@@ -179,6 +281,8 @@ func NotifyUserSubscription(poster models.Publisher, item models.Post, content s
 		"related_post": TruncatePostContent(item),
 	}
 
+	userIDs = FilterMutedAccounts(userIDs, item)
+
 	err := authkit.NotifyUserBatch(gap.Nx, userIDs, pushkit.Notification{
 		Topic:    "interactive.subscription",
 		Title:    nTitle,
@@ -228,6 +332,8 @@ func NotifyTagSubscription(poster models.Tag, og models.Publisher, item models.P
 		})
 	}
 
+	userIDs = FilterMutedAccounts(userIDs, item)
+
 	err := authkit.NotifyUserBatch(gap.Nx, userIDs, pushkit.Notification{
 		Topic:    "interactive.subscription",
 		Title:    nTitle,
@@ -276,6 +382,8 @@ func NotifyCategorySubscription(poster models.Category, og models.Publisher, ite
 		})
 	}
 
+	userIDs = FilterMutedAccounts(userIDs, item)
+
 	err := authkit.NotifyUserBatch(gap.Nx, userIDs, pushkit.Notification{
 		Topic:    "interactive.subscription",
 		Title:    nTitle,
@@ -67,6 +67,11 @@ func main() {
 	// Configure timed tasks
 	quartz := cron.New(cron.WithLogger(cron.VerbosePrintfLogger(&log.Logger)))
 	quartz.AddFunc("@every 5m", services.FlushPostViews)
+	quartz.AddFunc("@every 1m", services.PublishScheduledPosts)
+	quartz.AddFunc("@every 5m", services.HandleExpiredPosts)
+	quartz.AddFunc("@every 5m", services.HandleExpiredCollapseReviews)
+	quartz.AddFunc("@every 1m", services.FlushActivityPubDeliveries)
+	quartz.AddFunc("@every 10m", services.FetchFediverseFriendsTimeline)
 	quartz.Start()
 
 	// App